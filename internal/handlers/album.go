@@ -18,12 +18,21 @@ type AlbumDetailResponse struct {
 	OwnerName  string                 `json:"ownerName" :"owner_name"`   // here: artist name
 	OwnerImage string                 `json:"ownerImage" :"owner_image"` // could be blank or artist image
 	Duration   string                 `json:"duration" :"duration"`      // total playtime, e.g. "42m 15s"
-	Tracks     []models.TrackResponse `json:"tracks" :"tracks"`
+	Tracks     []models.TrackResponse `json:"tracks" :"tracks"`          // in disc, then track order
+
+	AlbumType            string             `json:"album_type"`
+	ReleaseDate          string             `json:"release_date"`           // "2020", "2020-03" or "2020-03-20"
+	ReleaseDatePrecision string             `json:"release_date_precision"` // "year" | "month" | "day"
+	Label                string             `json:"label"`
+	Copyrights           []models.Copyright `json:"copyrights"`
+	TotalTracks          int                `json:"total_tracks"`
 }
 
 // GetAlbumDetail loads an album and its tracks + artist, then returns a unified response.
 func GetAlbumDetail(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
 		// parse album ID
 		rawID := c.Param("id")
 		albumID, err := strconv.Atoi(rawID)
@@ -36,6 +45,9 @@ func GetAlbumDetail(db *gorm.DB) gin.HandlerFunc {
 		var album models.Album
 		if err := db.
 			Preload("Artist").
+			Preload("Songs", func(db *gorm.DB) *gorm.DB {
+				return db.Order("disc_number, track_number, id")
+			}).
 			Preload("Songs.Artist").
 			First(&album, "album_id = ?", albumID).
 			Error; err != nil {
@@ -55,14 +67,26 @@ func GetAlbumDetail(db *gorm.DB) gin.HandlerFunc {
 		tracks := make([]models.TrackResponse, len(album.Songs))
 		for i, s := range album.Songs {
 			tracks[i] = models.TrackResponse{
-				ID:         s.ID,
-				Title:      s.Title,
-				Artist:     s.Artist.Name,
-				AlbumArt:   album.Cover,
-				Duration:   s.Duration,
-				Downloaded: false,
+				ID:       s.ID,
+				Title:    s.Title,
+				Artist:   s.Artist.Name,
+				AlbumArt: album.Cover,
+				Duration: s.Duration,
+
+				DiscNumber:  s.DiscNumber,
+				TrackNumber: s.TrackNumber,
 			}
 		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
 
 		resp := AlbumDetailResponse{
 			ID:         album.AlbumId,
@@ -72,6 +96,13 @@ func GetAlbumDetail(db *gorm.DB) gin.HandlerFunc {
 			OwnerImage: "", // if you have an artist image, fill here
 			Duration:   durationStr,
 			Tracks:     tracks,
+
+			AlbumType:            album.AlbumType,
+			ReleaseDate:          album.ReleaseDate,
+			ReleaseDatePrecision: album.DatePrecision(),
+			Label:                album.Label,
+			Copyrights:           album.CopyrightList(),
+			TotalTracks:          len(album.Songs),
 		}
 		c.JSON(http.StatusOK, resp)
 	}
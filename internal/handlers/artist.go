@@ -2,28 +2,55 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
 	"spotify-mock-api/internal/models"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+const artistTopTracksLimit = 10
+
+// artistAlbumGroups are the ?include_groups= values, in the order the
+// artist page lists them.
+var artistAlbumGroups = []string{"album", "single", "compilation", "appears_on"}
+
 // ArtistDetailResponse matches the same shape
 type ArtistDetailResponse struct {
-	ID         int                    `json:"id"`
-	Title      string                 `json:"title"`      // artist name
-	Cover      string                 `json:"cover"`      // could be a default artist image
-	OwnerName  string                 `json:"ownerName"`  // blank or same as title
-	OwnerImage string                 `json:"ownerImage"` // artist avatar
-	Duration   string                 `json:"duration"`   // sum of track durations
-	Tracks     []models.TrackResponse `json:"tracks"`
+	ID           int                    `json:"id"`
+	Title        string                 `json:"title"`        // artist name
+	Cover        string                 `json:"cover"`        // artist image
+	HeaderImage  string                 `json:"header_image"` // wide banner for the top of the page
+	OwnerName    string                 `json:"ownerName"`    // same as title
+	OwnerImage   string                 `json:"ownerImage"`   // artist avatar
+	Followers    int                    `json:"followers"`
+	Genres       []string               `json:"genres"`         // most common first
+	Duration     string                 `json:"duration"`       // sum of track durations
+	Tracks       []models.TrackResponse `json:"tracks"`         // top tracks
+	FansAlsoLike []ArtistResponse       `json:"fans_also_like"` // related artists
+}
+
+// ArtistAlbumResponse is one entry of GET /artists/:id/albums
+type ArtistAlbumResponse struct {
+	AlbumID     int    `json:"album_id"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Cover       string `json:"cover"`
+	AlbumType   string `json:"album_type"`
+	AlbumGroup  string `json:"album_group"` // album_type, or "appears_on" for other artists' albums
+	TotalTracks int    `json:"total_tracks"`
+	ReleaseDate string `json:"release_date"`
 }
 
-// GetArtistDetail loads an artist, their top songs (or all songs), then returns unified response.
+// GetArtistDetail loads an artist, their top songs, genres and related
+// artists, then returns unified response.
 func GetArtistDetail(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
 		// parse artist ID
 		rawID := c.Param("id")
 		artistID, err := strconv.Atoi(rawID)
@@ -32,47 +59,276 @@ func GetArtistDetail(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// fetch artist + songs + album (for art)
 		var artist models.Artist
-		if err := db.
-			Preload("Songs.Album").
-			Preload("Songs").
-			First(&artist, "artist_id = ?", artistID).
-			Error; err != nil {
+		if err := db.First(&artist, "artist_id = ?", artistID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "artist not found"})
 			return
 		}
 
-		// total duration
-		totalSecs := 0
-		for _, s := range artist.Songs {
-			totalSecs += s.Duration
+		// total duration of everything they perform on
+		var totalSecs int
+		if err := db.
+			Model(&models.Song{}).
+			Where("id IN (?)", artistSongIDs(db, artistID)).
+			Select("COALESCE(SUM(duration), 0)").
+			Scan(&totalSecs).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load tracks"})
+			return
 		}
 		dur := time.Duration(totalSecs) * time.Second
 		durationStr := dur.Truncate(time.Second).String()
 
-		// map tracks
-		tracks := make([]models.TrackResponse, len(artist.Songs))
-		for i, s := range artist.Songs {
-			tracks[i] = models.TrackResponse{
-				ID:         s.ID,
-				Title:      s.Title,
-				Artist:     artist.Name,
-				AlbumArt:   s.Album.Cover,
-				Duration:   s.Duration,
-				Downloaded: false,
-			}
+		tracks, err := artistTopTracks(db, artistID, artistTopTracksLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load tracks"})
+			return
+		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		genres, err := artistGenres(db, artistID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load genres"})
+			return
+		}
+		followers, err := artistFollowers(db, artist)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load followers"})
+			return
+		}
+		fansAlsoLike, err := relatedArtistResponses(db, artist.ArtistId, fansAlsoLikeLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load related artists"})
+			return
+		}
+
+		image := artist.Image
+		if image == "" {
+			image = "/media/artist-art.jpeg"
+		}
+		header := artist.HeaderImage
+		if header == "" {
+			header = image
 		}
 
 		resp := ArtistDetailResponse{
-			ID:         artist.ArtistId,
-			Title:      artist.Name,
-			Cover:      "", // optional artist cover
-			OwnerName:  "",
-			OwnerImage: artist.Image, // if you store one
-			Duration:   durationStr,
-			Tracks:     tracks,
+			ID:           artist.ArtistId,
+			Title:        artist.Name,
+			Cover:        image,
+			HeaderImage:  header,
+			OwnerName:    artist.Name,
+			OwnerImage:   image,
+			Followers:    followers,
+			Genres:       genres,
+			Duration:     durationStr,
+			Tracks:       tracks,
+			FansAlsoLike: fansAlsoLike,
 		}
 		c.JSON(http.StatusOK, resp)
 	}
 }
+
+// GET /artists/:id/top-tracks
+// Ranked by plays across all listeners; unplayed songs follow in catalog order.
+func GetArtistTopTracks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		artistID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid artist ID"})
+			return
+		}
+		var artist models.Artist
+		if err := db.First(&artist, "artist_id = ?", artistID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artist not found"})
+			return
+		}
+
+		tracks, err := artistTopTracks(db, artistID, artistTopTracksLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load tracks"})
+			return
+		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"tracks": tracks})
+	}
+}
+
+// GET /artists/:id/albums?include_groups=album,single&limit=20&offset=0
+func GetArtistAlbums(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		artistID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid artist ID"})
+			return
+		}
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+
+		groups := make(map[string]bool)
+		for _, g := range parseNameList(c.DefaultQuery("include_groups", strings.Join(artistAlbumGroups, ","))) {
+			if !containsString(artistAlbumGroups, g) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "include_groups must list album, single, compilation or appears_on"})
+				return
+			}
+			groups[g] = true
+		}
+
+		var artist models.Artist
+		if err := db.First(&artist, "artist_id = ?", artistID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artist not found"})
+			return
+		}
+
+		// their own releases, plus other artists' albums they perform on
+		var own []int
+		if err := db.
+			Model(&models.AlbumCredit{}).
+			Where("artist_id = ? AND role = ?", artistID, models.RolePrimary).
+			Pluck("album_id", &own).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load albums"})
+			return
+		}
+		var albums []models.Album
+		if err := db.
+			Preload("Artist").
+			Where("artist_id = ? OR album_id IN ? OR album_id IN (?)", artistID, own,
+				db.Model(&models.Song{}).Select("album_id").Where("id IN (?)", artistSongIDs(db, artistID))).
+			Find(&albums).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load albums"})
+			return
+		}
+
+		var counts []struct {
+			AlbumID int
+			Tracks  int
+		}
+		if err := db.
+			Model(&models.Song{}).
+			Select("album_id, COUNT(*) AS tracks").
+			Group("album_id").
+			Scan(&counts).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load albums"})
+			return
+		}
+		trackCount := make(map[int]int, len(counts))
+		for _, n := range counts {
+			trackCount[n.AlbumID] = n.Tracks
+		}
+
+		items := make([]ArtistAlbumResponse, 0, len(albums))
+		for _, a := range albums {
+			group := a.AlbumType
+			if a.ArtistID != artistID && !containsInt(own, a.AlbumId) {
+				group = "appears_on"
+			}
+			if !groups[group] {
+				continue
+			}
+			items = append(items, ArtistAlbumResponse{
+				AlbumID:     a.AlbumId,
+				Title:       a.Title,
+				Artist:      a.Artist.Name,
+				Cover:       a.Cover,
+				AlbumType:   a.AlbumType,
+				AlbumGroup:  group,
+				TotalTracks: trackCount[a.AlbumId],
+				ReleaseDate: a.ReleaseDate,
+			})
+		}
+
+		// group by group, newest first within each
+		rank := make(map[string]int, len(artistAlbumGroups))
+		for i, g := range artistAlbumGroups {
+			rank[g] = i
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if rank[items[i].AlbumGroup] != rank[items[j].AlbumGroup] {
+				return rank[items[i].AlbumGroup] < rank[items[j].AlbumGroup]
+			}
+			if items[i].ReleaseDate != items[j].ReleaseDate {
+				return items[i].ReleaseDate > items[j].ReleaseDate
+			}
+			return items[i].AlbumID > items[j].AlbumID
+		})
+
+		start, end := pageBounds(len(items), limit, offset)
+		c.JSON(http.StatusOK, newPagingResponse(c, items[start:end], len(items), limit, offset))
+	}
+}
+
+// artistTopTracks ranks the songs the artist performs on by how often
+// anyone played them.
+func artistTopTracks(db *gorm.DB, artistID, limit int) ([]models.TrackResponse, error) {
+	var songs []models.Song
+	if err := db.
+		Preload("Artist").
+		Preload("Album").
+		Select("songs.*").
+		Joins("LEFT JOIN recent_plays ON recent_plays.reference_id = songs.id AND recent_plays.item_type = ?", "track").
+		Where("songs.id IN (?)", artistSongIDs(db, artistID)).
+		Group("songs.id").
+		Order("COUNT(recent_plays.id) DESC, songs.id").
+		Limit(limit).
+		Find(&songs).
+		Error; err != nil {
+		return nil, err
+	}
+	tracks := make([]models.TrackResponse, len(songs))
+	for i, s := range songs {
+		tracks[i] = newTrackResponse(s)
+	}
+	return tracks, nil
+}
+
+// artistGenres lists the genres of the songs the artist performs on, most
+// common first.
+func artistGenres(db *gorm.DB, artistID int) ([]string, error) {
+	genres := make([]string, 0)
+	err := db.
+		Model(&models.Genre{}).
+		Joins("JOIN song_genres ON song_genres.genre_id = genres.id").
+		Where("song_genres.song_id IN (?)", artistSongIDs(db, artistID)).
+		Group("genres.id").
+		Order("COUNT(*) DESC, genres.name").
+		Pluck("genres.name", &genres).
+		Error
+	return genres, err
+}
+
+// artistFollowers adds the users following the artist in their library
+// to the artist's seeded follower count.
+func artistFollowers(db *gorm.DB, artist models.Artist) (int, error) {
+	var follows int64
+	err := db.
+		Model(&models.LibraryEntry{}).
+		Where("type = ? AND reference_id = ?", "artist", strconv.Itoa(artist.ArtistId)).
+		Count(&follows).
+		Error
+	return artist.Followers + int(follows), err
+}
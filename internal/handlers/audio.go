@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AudioFeaturesResponse is the payload for GET /audio-features/:id
+type AudioFeaturesResponse struct {
+	models.AudioFeatures
+	Type        string `json:"type"`
+	URI         string `json:"uri"`
+	TrackHref   string `json:"track_href"`
+	AnalysisURL string `json:"analysis_url"`
+}
+
+// AudioAnalysisTrack summarizes the whole song in an analysis.
+type AudioAnalysisTrack struct {
+	Duration      float64 `json:"duration"` // seconds
+	Loudness      float64 `json:"loudness"`
+	Tempo         float64 `json:"tempo"`
+	Key           int     `json:"key"`
+	Mode          int     `json:"mode"`
+	TimeSignature int     `json:"time_signature"`
+}
+
+// AudioAnalysisResponse is the payload for GET /audio-analysis/:id
+type AudioAnalysisResponse struct {
+	Track    AudioAnalysisTrack `json:"track"`
+	Bars     json.RawMessage    `json:"bars"`
+	Beats    json.RawMessage    `json:"beats"`
+	Tatums   json.RawMessage    `json:"tatums"`
+	Sections json.RawMessage    `json:"sections"`
+}
+
+func newAudioFeaturesResponse(f models.AudioFeatures) AudioFeaturesResponse {
+	return AudioFeaturesResponse{
+		AudioFeatures: f,
+		Type:          "audio_features",
+		URI:           "spotify:track:" + strconv.Itoa(f.SongID),
+		TrackHref:     "/tracks/" + strconv.Itoa(f.SongID),
+		AnalysisURL:   "/audio-analysis/" + strconv.Itoa(f.SongID),
+	}
+}
+
+// loadAudioFeatures returns the stored features for the given songs,
+// generating and storing them for songs that have none yet. IDs that are
+// not songs are left out of the map.
+func loadAudioFeatures(db *gorm.DB, ids []int) (map[int]models.AudioFeatures, error) {
+	out := make(map[int]models.AudioFeatures, len(ids))
+
+	var stored []models.AudioFeatures
+	if err := db.Where("song_id IN ?", ids).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range stored {
+		out[f.SongID] = f
+	}
+
+	var missing []int
+	for _, id := range ids {
+		if _, ok := out[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	var songs []models.Song
+	if err := db.Preload("GenreTags").Where("id IN ?", missing).Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range songs {
+		f := models.GenerateAudioFeatures(s)
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&f).Error; err != nil {
+			return nil, err
+		}
+		out[s.ID] = f
+	}
+	return out, nil
+}
+
+// GET /audio-features/:id
+func GetAudioFeatures(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid track ID"})
+			return
+		}
+
+		features, err := loadAudioFeatures(db, []int{id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load audio features"})
+			return
+		}
+		f, ok := features[id]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+			return
+		}
+		c.JSON(http.StatusOK, newAudioFeaturesResponse(f))
+	}
+}
+
+// GET /audio-features?ids=1,2,3
+// Unknown IDs come back as null, in the position they were asked for.
+func GetSeveralAudioFeatures(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ids, err := parseIDList(c.Query("ids"))
+		if err != nil || len(ids) == 0 || len(ids) > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ids must list 1 to 100 track IDs"})
+			return
+		}
+
+		features, err := loadAudioFeatures(db, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load audio features"})
+			return
+		}
+
+		out := make([]*AudioFeaturesResponse, len(ids))
+		for i, id := range ids {
+			if f, ok := features[id]; ok {
+				resp := newAudioFeaturesResponse(f)
+				out[i] = &resp
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"audio_features": out})
+	}
+}
+
+// GET /audio-analysis/:id
+func GetAudioAnalysis(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid track ID"})
+			return
+		}
+
+		features, err := loadAudioFeatures(db, []int{id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load audio features"})
+			return
+		}
+		f, ok := features[id]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+			return
+		}
+
+		// analyses are generated from the features the first time they're asked for
+		var analysis models.AudioAnalysis
+		err = db.First(&analysis, "song_id = ?", id).Error
+		if err == gorm.ErrRecordNotFound {
+			if analysis, err = models.GenerateAudioAnalysis(f); err == nil {
+				err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&analysis).Error
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load audio analysis"})
+			return
+		}
+
+		c.JSON(http.StatusOK, AudioAnalysisResponse{
+			Track: AudioAnalysisTrack{
+				Duration:      float64(f.DurationMs) / 1000,
+				Loudness:      f.Loudness,
+				Tempo:         f.Tempo,
+				Key:           f.Key,
+				Mode:          f.Mode,
+				TimeSignature: f.TimeSignature,
+			},
+			Bars:     json.RawMessage(analysis.Bars),
+			Beats:    json.RawMessage(analysis.Beats),
+			Tatums:   json.RawMessage(analysis.Tatums),
+			Sections: json.RawMessage(analysis.Sections),
+		})
+	}
+}
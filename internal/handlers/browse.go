@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CategoryResponse is one tile of GET /browse/categories
+type CategoryResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Icon  string `json:"icon"`
+	Color string `json:"color"`
+	Href  string `json:"href"` // the category's playlists
+}
+
+// FeaturedPlaylistsResponse is the payload for GET /browse/featured-playlists
+type FeaturedPlaylistsResponse struct {
+	Message   string         `json:"message"` // greeting for the time of day
+	Playlists PagingResponse `json:"playlists"`
+}
+
+// newReleases orders albums newest release first; albums without a release
+// date come last, newest in the catalog first.
+func newReleases(db *gorm.DB) *gorm.DB {
+	return db.
+		Model(&models.Album{}).
+		Order("COALESCE(release_date, '') = ''").
+		Order("release_date DESC").
+		Order("album_id DESC")
+}
+
+// newAlbumResponse maps an album (with Artist preloaded) to a tile.
+func newAlbumResponse(a models.Album) AlbumResponse {
+	return AlbumResponse{
+		AlbumID:     a.AlbumId,
+		Title:       a.Title,
+		Artist:      a.Artist.Name,
+		Cover:       a.Cover,
+		AlbumType:   a.AlbumType,
+		ReleaseDate: a.ReleaseDate,
+	}
+}
+
+// GET /browse/new-releases?limit=20&offset=0
+func GetNewReleases(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+
+		var total int64
+		if err := db.Model(&models.Album{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load albums"})
+			return
+		}
+		var albums []models.Album
+		if err := newReleases(db).
+			Preload("Artist").
+			Limit(limit).
+			Offset(offset).
+			Find(&albums).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load albums"})
+			return
+		}
+
+		items := make([]AlbumResponse, len(albums))
+		for i, a := range albums {
+			items[i] = newAlbumResponse(a)
+		}
+		c.JSON(http.StatusOK, gin.H{"albums": newPagingResponse(c, items, int(total), limit, offset)})
+	}
+}
+
+// GET /browse/categories?limit=20&offset=0
+func GetCategories(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+
+		var total int64
+		if err := db.Model(&models.Category{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load categories"})
+			return
+		}
+		var cats []models.Category
+		if err := db.
+			Order("position, id").
+			Limit(limit).
+			Offset(offset).
+			Find(&cats).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load categories"})
+			return
+		}
+
+		items := make([]CategoryResponse, len(cats))
+		for i, cat := range cats {
+			items[i] = CategoryResponse{
+				ID:    cat.ID,
+				Name:  cat.Name,
+				Icon:  cat.Icon,
+				Color: cat.Color,
+				Href:  "/browse/categories/" + cat.ID + "/playlists",
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"categories": newPagingResponse(c, items, int(total), limit, offset)})
+	}
+}
+
+// GET /browse/categories/:id/playlists?limit=20&offset=0
+func GetCategoryPlaylists(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+
+		var cat models.Category
+		if err := db.First(&cat, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+			return
+		}
+
+		q := db.
+			Model(&models.Playlist{}).
+			Joins("JOIN category_playlists ON category_playlists.playlist_id = playlists.id").
+			Where("category_playlists.category_id = ?", cat.ID)
+		var total int64
+		if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load playlists"})
+			return
+		}
+		var pls []models.Playlist
+		if err := q.Order("playlists.id").Limit(limit).Offset(offset).Find(&pls).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load playlists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"playlists": newPagingResponse(c, editorialPlaylistResponses(pls), int(total), limit, offset)})
+	}
+}
+
+// GET /browse/featured-playlists?timestamp=2025-05-12T09:00:00&limit=20&offset=0
+// The greeting follows ?timestamp= (the client's local time) when given,
+// the server's clock otherwise.
+func GetFeaturedPlaylists(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+		now := time.Now()
+		if ts := c.Query("timestamp"); ts != "" {
+			t, err := time.Parse("2006-01-02T15:04:05", ts)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "timestamp must look like 2025-05-12T09:00:00"})
+				return
+			}
+			now = t
+		}
+
+		q := db.Model(&models.Playlist{}).Where("featured = ?", true)
+		var total int64
+		if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load playlists"})
+			return
+		}
+		var pls []models.Playlist
+		if err := q.Order("id").Limit(limit).Offset(offset).Find(&pls).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load playlists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, FeaturedPlaylistsResponse{
+			Message:   greeting(now),
+			Playlists: newPagingResponse(c, editorialPlaylistResponses(pls), int(total), limit, offset),
+		})
+	}
+}
+
+// greeting picks the featured playlists message for the hour of t.
+func greeting(t time.Time) string {
+	switch h := t.Hour(); {
+	case h >= 5 && h < 12:
+		return "Good morning"
+	case h >= 12 && h < 18:
+		return "Good afternoon"
+	default:
+		return "Good evening"
+	}
+}
+
+// editorialPlaylistResponses maps playlists to tiles, subtitled with their
+// description.
+func editorialPlaylistResponses(pls []models.Playlist) []PlaylistResponse {
+	out := make([]PlaylistResponse, len(pls))
+	for i, p := range pls {
+		out[i] = PlaylistResponse{
+			ID:          p.ID,
+			Title:       p.Title,
+			Subtitle:    p.Description,
+			Cover:       p.Cover,
+			LastUpdated: p.LastUpdated,
+		}
+	}
+	return out
+}
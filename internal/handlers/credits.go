@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrackCreditsResponse is the "Show credits" sheet of a track
+type TrackCreditsResponse struct {
+	TrackID    int                  `json:"track_id"`
+	Title      string               `json:"title"`
+	Label      string               `json:"label"`      // record label, empty when unknown
+	Performers []models.TrackArtist `json:"performers"` // primary artists first, then featured
+	Writers    []models.TrackArtist `json:"writers"`
+	Producers  []models.TrackArtist `json:"producers"`
+}
+
+// GET /tracks/:id/credits
+func GetTrackCredits(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid track ID"})
+			return
+		}
+		var song models.Song
+		if err := db.Preload("Artist").Preload("Album").First(&song, "id = ?", trackID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+			return
+		}
+
+		var credits []models.SongCredit
+		if err := db.
+			Preload("Artist").
+			Where("song_id = ? AND role IN ?", trackID, models.PerformerRoles).
+			Order("role = 'featured', position").
+			Find(&credits).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load credits"})
+			return
+		}
+		var named []models.SongCreditName
+		if err := db.Where("song_id = ?", trackID).Order("role, position").Find(&named).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load credits"})
+			return
+		}
+		// writers and producers who also perform link to their artist page
+		names := make([]string, len(named))
+		for i, n := range named {
+			names[i] = strings.ToLower(n.Name)
+		}
+		var known []models.Artist
+		if len(names) > 0 {
+			if err := db.Where("LOWER(name) IN ?", names).Find(&known).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load credits"})
+				return
+			}
+		}
+		artistIDs := make(map[string]int, len(known))
+		for _, a := range known {
+			artistIDs[strings.ToLower(a.Name)] = a.ArtistId
+		}
+
+		label := song.Label
+		if label == "" {
+			label = song.Album.Label
+		}
+		resp := TrackCreditsResponse{
+			TrackID:    song.ID,
+			Title:      song.Title,
+			Label:      label,
+			Performers: []models.TrackArtist{},
+			Writers:    []models.TrackArtist{},
+			Producers:  []models.TrackArtist{},
+		}
+		for _, cr := range credits {
+			resp.Performers = append(resp.Performers, models.TrackArtist{ID: cr.ArtistID, Name: cr.Artist.Name, Role: cr.Role})
+		}
+		for _, n := range named {
+			a := models.TrackArtist{ID: artistIDs[strings.ToLower(n.Name)], Name: n.Name, Role: n.Role}
+			switch n.Role {
+			case models.RoleWriter:
+				resp.Writers = append(resp.Writers, a)
+			case models.RoleProducer:
+				resp.Producers = append(resp.Producers, a)
+			}
+		}
+		if len(resp.Performers) == 0 {
+			resp.Performers = append(resp.Performers, models.TrackArtist{
+				ID: song.ArtistID, Name: song.Artist.Name, Role: models.RolePrimary,
+			})
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// loadTrackArtists fills Artists on every track from its performer
+// credits. Tracks without credits fall back to their billed artist.
+func loadTrackArtists(db *gorm.DB, lists ...[]models.TrackResponse) error {
+	var ids []int
+	for _, tracks := range lists {
+		for _, t := range tracks {
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var credits []models.SongCredit
+	if err := db.
+		Preload("Artist").
+		Where("song_id IN ? AND role IN ?", ids, models.PerformerRoles).
+		Order("song_id, role = 'featured', position").
+		Find(&credits).
+		Error; err != nil {
+		return err
+	}
+	bySong := make(map[int][]models.TrackArtist)
+	for _, c := range credits {
+		bySong[c.SongID] = append(bySong[c.SongID], models.TrackArtist{
+			ID:   c.ArtistID,
+			Name: c.Artist.Name,
+			Role: c.Role,
+		})
+	}
+
+	for _, tracks := range lists {
+		for i := range tracks {
+			t := &tracks[i]
+			if artists, ok := bySong[t.ID]; ok {
+				t.Artists = artists
+			} else if t.ArtistID != 0 || t.Artist != "" {
+				t.Artists = []models.TrackArtist{{ID: t.ArtistID, Name: t.Artist, Role: models.RolePrimary}}
+			} else {
+				t.Artists = []models.TrackArtist{}
+			}
+		}
+	}
+	return nil
+}
+
+// artistSongIDs selects the IDs of songs the artist performs on, whether
+// billed or credited, for use as a subquery.
+func artistSongIDs(db *gorm.DB, artistID int) *gorm.DB {
+	return db.Raw(`
+		SELECT song_id FROM song_credits WHERE artist_id = ? AND role IN ?
+		UNION
+		SELECT id FROM songs WHERE artist_id = ?`,
+		artistID, models.PerformerRoles, artistID)
+}
+
+// songPerformers maps every song to the artists performing on it: its
+// performer credits, or its billed artist when it has none.
+func songPerformers(db *gorm.DB) (map[int][]int, error) {
+	var rows []struct {
+		SongID   int
+		ArtistID int
+	}
+	if err := db.Raw(`
+		SELECT song_id, artist_id FROM song_credits WHERE role IN ?
+		UNION
+		SELECT id, artist_id FROM songs
+		WHERE id NOT IN (SELECT song_id FROM song_credits WHERE role IN ?)`,
+		models.PerformerRoles, models.PerformerRoles).
+		Scan(&rows).
+		Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int][]int)
+	for _, r := range rows {
+		out[r.SongID] = append(out[r.SongID], r.ArtistID)
+	}
+	return out, nil
+}
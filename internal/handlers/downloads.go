@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// downloadTypes are the kinds of items that can be saved for offline use.
+var downloadTypes = []string{"track", "album", "playlist", "episode"}
+
+type downloadRequest struct {
+	Type   string `json:"type" binding:"required"`
+	IDs    []int  `json:"ids" binding:"required"`
+	ShowID int    `json:"show_id"` // optional for episodes: checked against their show
+}
+
+// DownloadResponse is one entry of GET /me/downloads
+type DownloadResponse struct {
+	Type         string    `json:"type"`
+	ID           int       `json:"id"`
+	ShowID       int       `json:"show_id,omitempty"`
+	DeviceID     string    `json:"device_id"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// deviceID identifies the device a request comes from, via the
+// X-Device-ID header or ?device_id=, falling back to "default".
+func deviceID(c *gin.Context) string {
+	if id := c.GetHeader("X-Device-ID"); id != "" {
+		return id
+	}
+	return c.DefaultQuery("device_id", "default")
+}
+
+// GET /me/downloads
+func GetDownloads(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		var rows []models.Download
+		if err := db.
+			Where("user_id = ? AND device_id = ?", userID, deviceID(c)).
+			Order("created_at DESC").
+			Find(&rows).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+
+		resp := make([]DownloadResponse, len(rows))
+		for i, d := range rows {
+			resp[i] = DownloadResponse{
+				Type:         d.Type,
+				ID:           d.ReferenceID,
+				ShowID:       d.ParentID,
+				DeviceID:     d.DeviceID,
+				DownloadedAt: d.CreatedAt,
+			}
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// PUT /me/downloads
+// Body: { "type": "album", "ids": [1, 2] }
+func SaveDownloads(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		body, ok := bindDownloadRequest(c)
+		if !ok {
+			return
+		}
+
+		// episodes remember their show, so shows can tell they have downloads
+		shows := make(map[int]int)
+		if body.Type != "episode" {
+			missing, err := firstMissingID(db, body.Type, body.IDs)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot check " + body.Type + "s"})
+				return
+			}
+			if missing != 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s %d not found", body.Type, missing)})
+				return
+			}
+		} else {
+			var episodes []models.PodcastEpisode
+			if err := db.Where("id IN ?", body.IDs).Find(&episodes).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load episodes"})
+				return
+			}
+			for _, ep := range episodes {
+				shows[ep.ID] = ep.PodcastID
+			}
+			for _, id := range body.IDs {
+				showID, ok := shows[id]
+				if !ok {
+					c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("episode %d not found", id)})
+					return
+				}
+				if body.ShowID != 0 && showID != body.ShowID {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("episode %d is not in show %d", id, body.ShowID)})
+					return
+				}
+			}
+		}
+
+		rows := make([]models.Download, len(body.IDs))
+		for i, id := range body.IDs {
+			rows[i] = models.Download{
+				UserID:      userID,
+				DeviceID:    deviceID(c),
+				Type:        body.Type,
+				ReferenceID: id,
+				ParentID:    shows[id],
+			}
+		}
+		if err := db.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&rows).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save downloads"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DELETE /me/downloads
+// Body: { "type": "album", "ids": [1, 2] }
+func RemoveDownloads(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		body, ok := bindDownloadRequest(c)
+		if !ok {
+			return
+		}
+
+		if err := db.
+			Where("user_id = ? AND device_id = ? AND type = ? AND reference_id IN ?",
+				userID, deviceID(c), body.Type, body.IDs).
+			Delete(&models.Download{}).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not remove downloads"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// firstMissingID returns the first of ids with no track, album or playlist
+// behind it, or 0 when they all exist.
+func firstMissingID(db *gorm.DB, itemType string, ids []int) (int, error) {
+	var model interface{}
+	column := "id"
+	switch itemType {
+	case "track":
+		model = &models.Song{}
+	case "album":
+		model, column = &models.Album{}, "album_id"
+	case "playlist":
+		model = &models.Playlist{}
+	}
+	var found []int
+	if err := db.Model(model).Where(column+" IN ?", ids).Pluck(column, &found).Error; err != nil {
+		return 0, err
+	}
+	exists := make(map[int]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, id := range ids {
+		if !exists[id] {
+			return id, nil
+		}
+	}
+	return 0, nil
+}
+
+func bindDownloadRequest(c *gin.Context) (downloadRequest, bool) {
+	var body downloadRequest
+	if err := c.ShouldBindJSON(&body); err != nil || len(body.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type and ids are required"})
+		return body, false
+	}
+	if !containsString(downloadTypes, body.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be track, album, playlist or episode"})
+		return body, false
+	}
+	if body.Type != "episode" {
+		body.ShowID = 0
+	}
+	return body, true
+}
+
+// downloadSet answers "is this available offline?" for one user and device.
+type downloadSet struct {
+	tracks    map[int]bool // includes tracks of downloaded albums and playlists
+	albums    map[int]bool
+	playlists map[int]bool
+	episodes  map[int]bool
+	shows     map[int]bool // shows with at least one downloaded episode
+}
+
+// loadDownloads reads the user's downloads on the given device and expands
+// albums and playlists into the tracks they contain.
+func loadDownloads(db *gorm.DB, userID int, device string) (downloadSet, error) {
+	set := downloadSet{
+		tracks:    make(map[int]bool),
+		albums:    make(map[int]bool),
+		playlists: make(map[int]bool),
+		episodes:  make(map[int]bool),
+		shows:     make(map[int]bool),
+	}
+
+	var rows []models.Download
+	if err := db.
+		Where("user_id = ? AND device_id = ?", userID, device).
+		Find(&rows).
+		Error; err != nil {
+		return set, err
+	}
+
+	var albumIDs, playlistIDs []int
+	for _, d := range rows {
+		switch d.Type {
+		case "track":
+			set.tracks[d.ReferenceID] = true
+		case "album":
+			set.albums[d.ReferenceID] = true
+			albumIDs = append(albumIDs, d.ReferenceID)
+		case "playlist":
+			set.playlists[d.ReferenceID] = true
+			playlistIDs = append(playlistIDs, d.ReferenceID)
+		case "episode":
+			set.episodes[d.ReferenceID] = true
+			set.shows[d.ParentID] = true
+		}
+	}
+
+	var songIDs []int
+	if len(albumIDs) > 0 {
+		if err := db.Model(&models.Song{}).
+			Where("album_id IN ?", albumIDs).
+			Pluck("id", &songIDs).
+			Error; err != nil {
+			return set, err
+		}
+	}
+	if len(playlistIDs) > 0 {
+		var plSongIDs []int
+		if err := db.Model(&models.PlaylistSong{}).
+			Where("playlist_id IN ?", playlistIDs).
+			Pluck("song_id", &plSongIDs).
+			Error; err != nil {
+			return set, err
+		}
+		songIDs = append(songIDs, plSongIDs...)
+	}
+	for _, id := range songIDs {
+		set.tracks[id] = true
+	}
+	return set, nil
+}
+
+// markDownloaded sets Downloaded on every track in the slice.
+func (d downloadSet) markDownloaded(tracks []models.TrackResponse) {
+	for i := range tracks {
+		tracks[i].Downloaded = d.tracks[tracks[i].ID]
+	}
+}
+
+// showHasDownloads reports whether any episode of the show is downloaded.
+func (d downloadSet) showHasDownloads(showID int) bool {
+	return d.shows[showID]
+}
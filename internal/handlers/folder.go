@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type folderRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// folderUpdateRequest keeps parent_id raw to tell a missing key (keep the
+// parent) from null (move to the root).
+type folderUpdateRequest struct {
+	Name     string          `json:"name"`
+	ParentID json.RawMessage `json:"parent_id"`
+}
+
+// ListFolders handles GET /library/folders and returns the user's folders
+// flat; use GET /library for the nested view.
+func ListFolders(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		var folders []models.Folder
+		if err := db.
+			Where("user_id = ?", userID).
+			Order("name").
+			Find(&folders).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load folders"})
+			return
+		}
+		c.JSON(http.StatusOK, folders)
+	}
+}
+
+// GetFolder handles GET /library/folders/:id and returns the folder as a
+// library item with its playlists and sub-folders nested inside.
+func GetFolder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		sortBy := strings.ToLower(c.DefaultQuery("sort", librarySorts[0]))
+		if !containsString(librarySorts, sortBy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown sort"})
+			return
+		}
+		folder, ok := loadFolder(c, db, userID)
+		if !ok {
+			return
+		}
+
+		items, err := loadLibraryItems(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load library"})
+			return
+		}
+		for _, it := range buildLibraryTree(items, folder.ParentID) {
+			if it.Type == "folder" && it.ID == folder.ID {
+				sortLibraryItems(it.Children, sortBy)
+				c.JSON(http.StatusOK, it)
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
+	}
+}
+
+// POST /library/folders
+// Body: { "name": "Workout", "parent_id": 3 }
+func CreateFolder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		var body folderRequest
+		if err := c.ShouldBindJSON(&body); err != nil || body.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+		if body.ParentID != nil && !folderExists(db, userID, *body.ParentID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent folder not found"})
+			return
+		}
+
+		folder := models.Folder{
+			UserID:   userID,
+			Name:     body.Name,
+			ParentID: body.ParentID,
+		}
+		if err := db.Create(&folder).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create folder"})
+			return
+		}
+		c.JSON(http.StatusCreated, folder)
+	}
+}
+
+// PUT /library/folders/:id
+// Body: { "name": "New Name", "parent_id": null }
+// Renames the folder and moves it under parent_id (null = library root);
+// leave parent_id out to keep the folder where it is.
+func UpdateFolder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		folder, ok := loadFolder(c, db, userID)
+		if !ok {
+			return
+		}
+
+		var body folderUpdateRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.Name != "" {
+			folder.Name = body.Name
+		}
+
+		parentID := folder.ParentID
+		if len(body.ParentID) > 0 {
+			parentID = nil
+			if err := json.Unmarshal(body.ParentID, &parentID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id must be a folder ID or null"})
+				return
+			}
+		}
+		if parentID != nil {
+			if !folderExists(db, userID, *parentID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "parent folder not found"})
+				return
+			}
+			// a folder cannot end up inside itself
+			inside, err := folderIsWithin(db, *parentID, folder.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify folder parent"})
+				return
+			}
+			if inside {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cannot move a folder into itself"})
+				return
+			}
+		}
+		folder.ParentID = parentID
+
+		if err := db.Save(&folder).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update folder"})
+			return
+		}
+		c.JSON(http.StatusOK, folder)
+	}
+}
+
+// DELETE /library/folders/:id
+// The folder's playlists and sub-folders move up to its parent.
+func DeleteFolder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		folder, ok := loadFolder(c, db, userID)
+		if !ok {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Playlist{}).
+				Where("folder_id = ?", folder.ID).
+				UpdateColumn("folder_id", folder.ParentID).
+				Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Folder{}).
+				Where("parent_id = ?", folder.ID).
+				Update("parent_id", folder.ParentID).
+				Error; err != nil {
+				return err
+			}
+			if err := tx.
+				Where("user_id = ? AND type = ? AND reference_id = ?", userID, "folder", strconv.Itoa(folder.ID)).
+				Delete(&models.LibraryEntry{}).
+				Error; err != nil {
+				return err
+			}
+			return tx.Delete(&folder).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete folder"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// PUT /playlists/:id/folder
+// Body: { "folder_id": 3 } or { "folder_id": null } to move back to the root.
+func MovePlaylistToFolder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		plID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid playlist ID"})
+			return
+		}
+
+		var body struct {
+			FolderID *int `json:"folder_id"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if body.FolderID != nil && !folderExists(db, userID, *body.FolderID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "folder not found"})
+			return
+		}
+
+		var pl models.Playlist
+		if err := db.First(&pl, "id = ?", plID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "playlist not found"})
+			return
+		}
+		if pl.UserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only your own playlists can be filed in folders"})
+			return
+		}
+
+		if err := db.Model(&pl).
+			UpdateColumn("folder_id", body.FolderID). // moving isn't an edit, keep last_updated
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not move playlist"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// loadFolder reads :id and loads the user's folder, writing the error
+// response itself when that fails.
+func loadFolder(c *gin.Context, db *gorm.DB, userID int) (models.Folder, bool) {
+	var folder models.Folder
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid folder ID"})
+		return folder, false
+	}
+	if err := db.First(&folder, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
+		return folder, false
+	}
+	return folder, true
+}
+
+func folderExists(db *gorm.DB, userID, id int) bool {
+	var count int64
+	db.Model(&models.Folder{}).Where("id = ? AND user_id = ?", id, userID).Count(&count)
+	return count > 0
+}
+
+// folderIsWithin reports whether folder id is ancestor or one of its
+// descendants, by walking up the parent chain.
+func folderIsWithin(db *gorm.DB, id, ancestor int) (bool, error) {
+	seen := make(map[int]bool)
+	for !seen[id] {
+		if id == ancestor {
+			return true, nil
+		}
+		seen[id] = true
+
+		var f models.Folder
+		if err := db.First(&f, "id = ?", id).Error; err != nil {
+			return false, err
+		}
+		if f.ParentID == nil {
+			return false, nil
+		}
+		id = *f.ParentID
+	}
+	return false, nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+// treeShape renders items as "type-id" with children in brackets, e.g.
+// "folder-1[playlist-2] album-3".
+func treeShape(items []models.LibraryItem) string {
+	out := ""
+	for i, it := range items {
+		if i > 0 {
+			out += " "
+		}
+		out += it.Type + "-" + strconv.Itoa(it.ID)
+		if it.Type == "folder" {
+			out += "[" + treeShape(it.Children) + "]"
+		}
+	}
+	return out
+}
+
+func TestBuildLibraryTree(t *testing.T) {
+	played := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := played.Add(time.Hour)
+	tests := []struct {
+		name  string
+		items []models.LibraryItem
+		want  string
+	}{
+		{
+			name: "flat",
+			items: []models.LibraryItem{
+				{Type: "playlist", ID: 1},
+				{Type: "album", ID: 2},
+			},
+			want: "playlist-1 album-2",
+		},
+		{
+			name: "nested folders",
+			items: []models.LibraryItem{
+				{Type: "folder", ID: 1},
+				{Type: "folder", ID: 2, FolderID: intPtr(1)},
+				{Type: "playlist", ID: 3, FolderID: intPtr(2)},
+				{Type: "playlist", ID: 4, FolderID: intPtr(1)},
+				{Type: "playlist", ID: 5},
+			},
+			want: "folder-1[folder-2[playlist-3] playlist-4] playlist-5",
+		},
+		{
+			name: "empty folder",
+			items: []models.LibraryItem{
+				{Type: "folder", ID: 1},
+			},
+			want: "folder-1[]",
+		},
+		{
+			name: "items in a folder that isn't listed are left out",
+			items: []models.LibraryItem{
+				{Type: "playlist", ID: 1, FolderID: intPtr(9)},
+				{Type: "playlist", ID: 2},
+			},
+			want: "playlist-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := treeShape(buildLibraryTree(tt.items, nil)); got != tt.want {
+				t.Errorf("buildLibraryTree = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("folders count their items and take the latest play", func(t *testing.T) {
+		tree := buildLibraryTree([]models.LibraryItem{
+			{Type: "folder", ID: 1},
+			{Type: "playlist", ID: 2, FolderID: intPtr(1), LastPlayedAt: &played},
+			{Type: "playlist", ID: 3, FolderID: intPtr(1), LastPlayedAt: &later},
+		}, nil)
+		if len(tree) != 1 {
+			t.Fatalf("got %d root items, want 1", len(tree))
+		}
+		if tree[0].Subtitle != "Folder • 2 items" {
+			t.Errorf("Subtitle = %q, want %q", tree[0].Subtitle, "Folder • 2 items")
+		}
+		if tree[0].LastPlayedAt == nil || !tree[0].LastPlayedAt.Equal(later) {
+			t.Errorf("LastPlayedAt = %v, want %v", tree[0].LastPlayedAt, later)
+		}
+	})
+}
+
+func TestFolderIsWithin(t *testing.T) {
+	db := newTestDB(t, &models.Folder{})
+	// 1 ⊃ 2 ⊃ 3, 4 on its own, and 5 ⇄ 6 in a broken cycle
+	for _, f := range []models.Folder{
+		{ID: 1, UserID: 1},
+		{ID: 2, UserID: 1, ParentID: intPtr(1)},
+		{ID: 3, UserID: 1, ParentID: intPtr(2)},
+		{ID: 4, UserID: 1},
+		{ID: 5, UserID: 1, ParentID: intPtr(6)},
+		{ID: 6, UserID: 1, ParentID: intPtr(5)},
+	} {
+		if err := db.Create(&f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		id, ancestor int
+		want         bool
+	}{
+		{1, 1, true},
+		{2, 1, true},
+		{3, 1, true},
+		{3, 2, true},
+		{1, 3, false},
+		{4, 1, false},
+		{1, 4, false},
+		{5, 6, true},
+		{5, 1, false},
+	}
+	for _, tt := range tests {
+		got, err := folderIsWithin(db, tt.id, tt.ancestor)
+		if err != nil {
+			t.Fatalf("folderIsWithin(%d, %d): %v", tt.id, tt.ancestor, err)
+		}
+		if got != tt.want {
+			t.Errorf("folderIsWithin(%d, %d) = %v, want %v", tt.id, tt.ancestor, got, tt.want)
+		}
+	}
+
+	if _, err := folderIsWithin(db, 99, 1); err == nil {
+		t.Error("folderIsWithin of a missing folder succeeded, want an error")
+	}
+}
+
+func TestLoadLibraryItemsSavedPlaylistFolders(t *testing.T) {
+	db := newTestDB(t,
+		&models.User{}, &models.Playlist{}, &models.Album{}, &models.Podcast{},
+		&models.PodcastEpisode{}, &models.Folder{}, &models.LibraryEntry{},
+		&models.RecentPlay{}, &models.Download{},
+	)
+	rows := []interface{}{
+		&models.User{ID: 1, Name: "Me"},
+		&models.User{ID: 2, Name: "Friend"},
+		&models.Folder{ID: 1, UserID: 1, Name: "Mine"},
+		&models.Folder{ID: 2, UserID: 2, Name: "Theirs"},
+		&models.Playlist{ID: 10, UserID: 1, Title: "Filed", FolderID: intPtr(1)},
+		&models.Playlist{ID: 11, UserID: 2, Title: "Saved", FolderID: intPtr(2)},
+		&models.Playlist{ID: 12, UserID: 2, Title: "Not saved"},
+		&models.LibraryEntry{UserID: 1, Type: "playlist", ReferenceID: "11"},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := loadLibraryItems(db, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := treeShape(buildLibraryTree(items, nil)), "playlist-11 folder-1[playlist-10]"; got != want {
+		t.Errorf("library tree = %s, want %s", got, want)
+	}
+}
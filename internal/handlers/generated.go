@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	generatorDailyMix       = "daily_mix"
+	generatorDiscoverWeekly = "discover_weekly"
+	// generatorWrapped marks a saved Wrapped top songs playlist; it belongs
+	// to the listener and is never regenerated in the background.
+	generatorWrapped = "wrapped"
+
+	dailyMixCount        = 6
+	dailyMixMinTracks    = 5
+	dailyMixMaxTracks    = 30
+	discoverWeeklyTracks = 30
+	// discoverPerArtist keeps any one related artist from taking over
+	// Discover Weekly.
+	discoverPerArtist = 2
+)
+
+// GeneratedPlaylistResponse is one entry of GET /me/made-for-you
+type GeneratedPlaylistResponse struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Cover       string    `json:"cover"`
+	Generator   string    `json:"generator"`
+	Version     int       `json:"version"`
+	Tracks      int       `json:"tracks"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// PlaylistVersionResponse is one entry of GET /playlists/:id/versions, and
+// with Tracks filled in, the payload for GET /playlists/:id/versions/:version
+type PlaylistVersionResponse struct {
+	Version     int                    `json:"version"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	TrackCount  int                    `json:"track_count"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Tracks      []models.TrackResponse `json:"tracks,omitempty"`
+}
+
+// generatorMu keeps the background generator and refresh requests from
+// rebuilding playlists at the same time.
+var generatorMu sync.Mutex
+
+// generatedMix is a playlist the generator wants to publish.
+type generatedMix struct {
+	generator   string
+	title       string
+	description string
+	songIDs     []int
+}
+
+// StartPlaylistGenerator refreshes every listener's generated playlists now
+// and then once per interval, in the background.
+func StartPlaylistGenerator(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			if err := generateAllPlaylists(db); err != nil {
+				log.Printf("playlist generator: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// generateAllPlaylists runs the generator for every user with plays.
+func generateAllPlaylists(db *gorm.DB) error {
+	var userIDs []int
+	if err := db.Model(&models.RecentPlay{}).Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range userIDs {
+		if err := generatePlaylists(db, id, "", false); err != nil {
+			return fmt.Errorf("user %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// generatePlaylists builds the user's Daily Mixes and Discover Weekly and
+// saves any that changed. only limits the run to one generator ("" = all).
+// Discover Weekly is rebuilt once a week, from Monday, unless force is set.
+func generatePlaylists(db *gorm.DB, userID int, only string, force bool) error {
+	generatorMu.Lock()
+	defer generatorMu.Unlock()
+
+	owner := models.User{Name: models.SystemUserName}
+	if err := db.Where(&owner).Attrs(models.User{Image: "/media/playlist-art.jpg"}).FirstOrCreate(&owner).Error; err != nil {
+		return err
+	}
+
+	rng := statsRanges["medium_term"]
+	stats, err := loadListeningStats(db, userID, time.Now().Add(-rng.window), time.Time{}, rng.halfLife)
+	if err != nil {
+		return err
+	}
+	var catalog []models.Song
+	if err := db.Preload("Artist").Preload("GenreTags").Order("id").Find(&catalog).Error; err != nil {
+		return err
+	}
+
+	var mixes []generatedMix
+	if only == "" || only == generatorDailyMix {
+		mixes = append(mixes, buildDailyMixes(stats, catalog)...)
+	}
+	if only == "" || only == generatorDiscoverWeekly {
+		due := force
+		if !due {
+			var current models.Playlist
+			err := db.Where("made_for_id = ? AND generator = ?", userID, generatorDiscoverWeekly).First(&current).Error
+			if err == gorm.ErrRecordNotFound {
+				due = true
+			} else if err != nil {
+				return err
+			} else {
+				due = current.LastUpdated.Before(startOfWeek(time.Now()))
+			}
+		}
+		if due {
+			mix, err := buildDiscoverWeekly(db, userID, stats, catalog)
+			if err != nil {
+				return err
+			}
+			if len(mix.songIDs) > 0 {
+				mixes = append(mixes, mix)
+			}
+		}
+	}
+
+	titles := []string{""}
+	for _, mix := range mixes {
+		if err := saveGeneratedPlaylist(db, owner.ID, userID, mix, force); err != nil {
+			return err
+		}
+		if mix.generator == generatorDailyMix {
+			titles = append(titles, mix.title)
+		}
+	}
+	if only == "" || only == generatorDailyMix {
+		// fewer genre clusters than last time: drop the mixes left over
+		var stale []int
+		if err := db.
+			Model(&models.Playlist{}).
+			Where("made_for_id = ? AND generator = ? AND title NOT IN ?", userID, generatorDailyMix, titles).
+			Pluck("id", &stale).
+			Error; err != nil {
+			return err
+		}
+		if err := deleteGeneratedPlaylists(db, stale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteGeneratedPlaylists removes generated playlists along with their
+// tracks, versions, library entries and downloads.
+func deleteGeneratedPlaylists(db *gorm.DB, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	refs := make([]string, len(ids))
+	for i, id := range ids {
+		refs[i] = strconv.Itoa(id)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("playlist_id IN ?", ids).Delete(&models.PlaylistSong{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("playlist_id IN ?", ids).Delete(&models.PlaylistVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("type = ? AND reference_id IN ?", "playlist", refs).Delete(&models.LibraryEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("type = ? AND reference_id IN ?", "playlist", ids).Delete(&models.Download{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Playlist{}, ids).Error
+	})
+}
+
+// startOfWeek is Monday 00:00 of t's week, in local time.
+func startOfWeek(t time.Time) time.Time {
+	t = t.In(time.Local)
+	offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+// buildDailyMixes clusters the user's genres and fills one mix per cluster
+// with favourites and unheard songs from those genres, alternating. A
+// genre joins the cluster of the first, better-ranked genre it shares a
+// song with; songs go to the first mix that can take them.
+func buildDailyMixes(stats listeningStats, catalog []models.Song) []generatedMix {
+	ranked := rankNames(stats.genres)
+	if len(ranked) == 0 {
+		return nil
+	}
+
+	// which genres appear together on a song
+	together := make(map[string]map[string]bool)
+	for _, s := range catalog {
+		for _, a := range s.GenreTags {
+			if together[a.Name] == nil {
+				together[a.Name] = make(map[string]bool)
+			}
+			for _, b := range s.GenreTags {
+				together[a.Name][b.Name] = true
+			}
+		}
+	}
+
+	var clusters [][]string
+	assigned := make(map[string]bool)
+	for _, lead := range ranked {
+		if assigned[lead] || len(clusters) == dailyMixCount {
+			continue
+		}
+		cluster := []string{lead}
+		assigned[lead] = true
+		for _, g := range ranked {
+			if !assigned[g] && together[lead][g] {
+				cluster = append(cluster, g)
+				assigned[g] = true
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	used := make(map[int]bool)
+	var mixes []generatedMix
+	for _, cluster := range clusters {
+		inCluster := make(map[string]bool, len(cluster))
+		for _, g := range cluster {
+			inCluster[g] = true
+		}
+
+		var heard, fresh []models.Song
+		for _, s := range catalog {
+			if used[s.ID] {
+				continue
+			}
+			for _, g := range s.GenreTags {
+				if inCluster[g.Name] {
+					if stats.tracks[s.ID] > 0 {
+						heard = append(heard, s)
+					} else {
+						fresh = append(fresh, s)
+					}
+					break
+				}
+			}
+		}
+		if len(heard) == 0 || len(heard)+len(fresh) < dailyMixMinTracks {
+			continue
+		}
+		sort.SliceStable(heard, func(i, j int) bool {
+			return stats.tracks[heard[i].ID] > stats.tracks[heard[j].ID]
+		})
+		sort.SliceStable(fresh, func(i, j int) bool {
+			return stats.artists[fresh[i].ArtistID] > stats.artists[fresh[j].ArtistID]
+		})
+
+		var ids []int
+		artistScore := make(map[int]float64)
+		names := make(map[int]string)
+		for i := 0; len(ids) < dailyMixMaxTracks && (i < len(heard) || i < len(fresh)); i++ {
+			for _, list := range [][]models.Song{heard, fresh} {
+				if i < len(list) && len(ids) < dailyMixMaxTracks {
+					s := list[i]
+					ids = append(ids, s.ID)
+					used[s.ID] = true
+					artistScore[s.ArtistID] += stats.artists[s.ArtistID] + 0.001
+					names[s.ArtistID] = s.Artist.Name
+				}
+			}
+		}
+
+		var top []string
+		for _, id := range firstN(rankIDs(artistScore), 3) {
+			top = append(top, names[id])
+		}
+		mixes = append(mixes, generatedMix{
+			generator:   generatorDailyMix,
+			title:       fmt.Sprintf("Daily Mix %d", len(mixes)+1),
+			description: strings.Join(top, ", ") + " and more",
+			songIDs:     ids,
+		})
+	}
+	return mixes
+}
+
+// buildDiscoverWeekly picks songs the user has never played from the
+// artists most related to the ones they listen to.
+func buildDiscoverWeekly(db *gorm.DB, userID int, stats listeningStats, catalog []models.Song) (generatedMix, error) {
+	mix := generatedMix{
+		generator:   generatorDiscoverWeekly,
+		title:       "Discover Weekly",
+		description: "Your weekly mixtape of fresh music. Enjoy new music and deep cuts picked for you. Updates every Monday.",
+	}
+
+	var played []int
+	if err := db.
+		Model(&models.RecentPlay{}).
+		Where("user_id = ? AND item_type = ?", userID, "track").
+		Distinct("reference_id").
+		Pluck("reference_id", &played).
+		Error; err != nil {
+		return mix, err
+	}
+	heard := make(map[int]bool, len(played))
+	for _, id := range played {
+		heard[id] = true
+	}
+
+	// artists related to the ones the user plays, weighted by how much
+	related := make(map[int]float64)
+	scores, err := loadRelatedArtists(db)
+	if err != nil {
+		return mix, err
+	}
+	for seed, weight := range stats.artists {
+		for id, score := range scores[seed] {
+			if _, ok := stats.artists[id]; !ok {
+				related[id] += weight * score
+			}
+		}
+	}
+	perArtist := make(map[int]int)
+	for _, artistID := range rankIDs(related) {
+		for _, s := range catalog {
+			if len(mix.songIDs) == discoverWeeklyTracks {
+				return mix, nil
+			}
+			if s.ArtistID != artistID || heard[s.ID] || perArtist[artistID] == discoverPerArtist {
+				continue
+			}
+			mix.songIDs = append(mix.songIDs, s.ID)
+			perArtist[artistID]++
+		}
+	}
+	return mix, nil
+}
+
+// saveGeneratedPlaylist publishes mix as the user's playlist of that
+// title, keeping the previous track list as a version. Unchanged mixes are
+// left alone unless force is set.
+func saveGeneratedPlaylist(db *gorm.DB, ownerID, userID int, mix generatedMix, force bool) error {
+	var pl models.Playlist
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(models.Playlist{UserID: ownerID, MadeForID: &userID, Generator: mix.generator, Title: mix.title}).
+			Attrs(models.Playlist{Cover: "/media/playlist-art.jpg"}).
+			FirstOrCreate(&pl).
+			Error; err != nil {
+			return err
+		}
+
+		// the latest version keeps the track order; playlist_songs doesn't
+		if pl.Version > 0 && !force {
+			var latest models.PlaylistVersion
+			if err := tx.
+				Where("playlist_id = ? AND version = ?", pl.ID, pl.Version).
+				First(&latest).
+				Error; err != nil {
+				return err
+			}
+			var current []int
+			if err := json.Unmarshal(latest.SongIDs, &current); err != nil {
+				return err
+			}
+			if sameIDs(current, mix.songIDs) && latest.Description == mix.description {
+				return nil
+			}
+		}
+
+		songIDs, err := json.Marshal(mix.songIDs)
+		if err != nil {
+			return err
+		}
+		version := models.PlaylistVersion{
+			PlaylistID:  pl.ID,
+			Version:     pl.Version + 1,
+			Title:       mix.title,
+			Description: mix.description,
+			SongIDs:     songIDs,
+		}
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("playlist_id = ?", pl.ID).Delete(&models.PlaylistSong{}).Error; err != nil {
+			return err
+		}
+		for i, id := range mix.songIDs {
+			if err := tx.
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.PlaylistSong{PlaylistID: pl.ID, SongID: id, Position: i + 1}).
+				Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&pl).Updates(map[string]interface{}{
+			"description":  mix.description,
+			"version":      version.Version,
+			"last_updated": version.CreatedAt,
+		}).Error
+	})
+}
+
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// listGeneratedPlaylists returns the user's generated playlists, Daily
+// Mixes first.
+func listGeneratedPlaylists(db *gorm.DB, userID int) ([]GeneratedPlaylistResponse, error) {
+	var pls []models.Playlist
+	if err := db.
+		Where("made_for_id = ?", userID).
+		Order("generator, title").
+		Find(&pls).
+		Error; err != nil {
+		return nil, err
+	}
+	out := make([]GeneratedPlaylistResponse, len(pls))
+	for i, p := range pls {
+		var count int64
+		if err := db.Model(&models.PlaylistSong{}).Where("playlist_id = ?", p.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		out[i] = GeneratedPlaylistResponse{
+			ID:          p.ID,
+			Title:       p.Title,
+			Description: p.Description,
+			Cover:       p.Cover,
+			Generator:   p.Generator,
+			Version:     p.Version,
+			Tracks:      int(count),
+			LastUpdated: p.LastUpdated,
+		}
+	}
+	return out, nil
+}
+
+// GET /me/made-for-you
+func GetMadeForYou(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		out, err := listGeneratedPlaylists(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load playlists"})
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// POST /me/made-for-you/refresh?generator=daily_mix|discover_weekly
+// Regenerates now, even if nothing changed or Discover Weekly isn't due.
+func RefreshMadeForYou(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		only := c.Query("generator")
+		if only != "" && only != generatorDailyMix && only != generatorDiscoverWeekly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "generator must be daily_mix or discover_weekly"})
+			return
+		}
+		if err := generatePlaylists(db, userID, only, true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate playlists"})
+			return
+		}
+
+		out, err := listGeneratedPlaylists(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load playlists"})
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// GET /playlists/:id/versions
+func GetPlaylistVersions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var versions []models.PlaylistVersion
+		if err := db.
+			Where("playlist_id = ?", c.Param("id")).
+			Order("version DESC").
+			Find(&versions).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load versions"})
+			return
+		}
+
+		out := make([]PlaylistVersionResponse, len(versions))
+		for i, v := range versions {
+			var ids []int
+			_ = json.Unmarshal(v.SongIDs, &ids)
+			out[i] = PlaylistVersionResponse{
+				Version:     v.Version,
+				Title:       v.Title,
+				Description: v.Description,
+				TrackCount:  len(ids),
+				CreatedAt:   v.CreatedAt,
+			}
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// GET /playlists/:id/versions/:version
+func GetPlaylistVersion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+			return
+		}
+		var v models.PlaylistVersion
+		if err := db.
+			Where("playlist_id = ? AND version = ?", c.Param("id"), version).
+			First(&v).
+			Error; err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load version"})
+			return
+		}
+
+		var ids []int
+		if err := json.Unmarshal(v.SongIDs, &ids); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "corrupt version"})
+			return
+		}
+		var songs []models.Song
+		if err := db.Preload("Artist").Preload("Album").Where("id IN ?", ids).Find(&songs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load tracks"})
+			return
+		}
+		byID := make(map[int]models.Song, len(songs))
+		for _, s := range songs {
+			byID[s.ID] = s
+		}
+		tracks := make([]models.TrackResponse, 0, len(ids))
+		for _, id := range ids {
+			if s, ok := byID[id]; ok {
+				tracks = append(tracks, newTrackResponse(s))
+			}
+		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, PlaylistVersionResponse{
+			Version:     v.Version,
+			Title:       v.Title,
+			Description: v.Description,
+			TrackCount:  len(ids),
+			CreatedAt:   v.CreatedAt,
+			Tracks:      tracks,
+		})
+	}
+}
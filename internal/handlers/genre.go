@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"spotify-mock-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GenreResponse is one entry of GET /genres
+type GenreResponse struct {
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Tracks int    `json:"tracks"`
+}
+
+// findGenre looks a genre up by slug or display name ("hip-hop" and
+// "Hip Hop" find the same row).
+func findGenre(db *gorm.DB, nameOrSlug string) (models.Genre, error) {
+	var g models.Genre
+	err := db.Where("slug = ?", models.GenreSlug(nameOrSlug)).First(&g).Error
+	return g, err
+}
+
+// GET /genres
+func ListGenres(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		out := make([]GenreResponse, 0)
+		if err := db.
+			Model(&models.Genre{}).
+			Select("genres.name, genres.slug, COUNT(song_genres.song_id) AS tracks").
+			Joins("LEFT JOIN song_genres ON song_genres.genre_id = genres.id").
+			Group("genres.id").
+			Order("genres.slug").
+			Scan(&out).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load genres"})
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// GET /recommendations/available-genre-seeds
+func GetAvailableGenreSeeds(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slugs := make([]string, 0)
+		if err := db.Model(&models.Genre{}).Order("slug").Pluck("slug", &slugs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load genres"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"genres": slugs})
+	}
+}
+
+// GET /genres/:name/tracks?limit=20&offset=0
+// :name may be the slug or the display name.
+func GetGenreTracks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+
+		genre, err := findGenre(db, c.Param("name"))
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "genre not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load genre"})
+			return
+		}
+
+		inGenre := db.
+			Model(&models.Song{}).
+			Joins("JOIN song_genres ON song_genres.song_id = songs.id").
+			Where("song_genres.genre_id = ?", genre.ID).
+			Session(&gorm.Session{})
+
+		var total int64
+		if err := inGenre.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load tracks"})
+			return
+		}
+		var songs []models.Song
+		if err := inGenre.
+			Preload("Artist").
+			Preload("Album").
+			Order("songs.id").
+			Limit(limit).
+			Offset(offset).
+			Find(&songs).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load tracks"})
+			return
+		}
+
+		items := make([]models.TrackResponse, 0, len(songs))
+		for _, s := range songs {
+			items = append(items, newTrackResponse(s))
+		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(items)
+		if err := loadTrackArtists(db, items); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, newPagingResponse(c, items, int(total), limit, offset))
+	}
+}
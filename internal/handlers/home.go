@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// jumpBackInAge is how long ago something must have been played to show
+// up in "Jump back in" rather than "Recently played".
+const jumpBackInAge = 7 * day
+
+// HomeSection is one row of the home screen. Type names the kind of
+// items; Layout tells the client how to draw them.
+type HomeSection struct {
+	ID     string      `json:"id"`
+	Title  string      `json:"title"`
+	Type   string      `json:"type"`   // "recent" | "track" | "newsletter" | "album"
+	Layout string      `json:"layout"` // "grid" | "carousel" | "cards"
+	Href   string      `json:"href,omitempty"`
+	Items  interface{} `json:"items"`
+}
+
+// HomeResponse is the payload for GET /home
+type HomeResponse struct {
+	Sections []HomeSection `json:"sections"`
+}
+
+// homeSection describes one section of the home layout and how to fill it.
+type homeSection struct {
+	HomeSection
+	limit int
+	// load returns the section's items and how many there are
+	load func(h *homeBuilder, limit int) (interface{}, int, error)
+}
+
+// homeLayout is the home screen, top to bottom.
+var homeLayout = []homeSection{
+	{
+		HomeSection: HomeSection{ID: "recently_played", Title: "Recently played", Type: "recent", Layout: "grid", Href: "/me/player/recently-played"},
+		limit:       8,
+		load: func(h *homeBuilder, limit int) (interface{}, int, error) {
+			items, err := loadRecentItems(h.db, h.userID, time.Time{}, limit, nil)
+			for _, it := range items {
+				h.shown[recentItemKey(it.Type, it.ID)] = true
+			}
+			return items, len(items), err
+		},
+	},
+	{
+		HomeSection: HomeSection{ID: "made_for_you", Title: "Made for you", Type: "track", Layout: "carousel", Href: "/me/recommendations"},
+		limit:       10,
+		load: func(h *homeBuilder, limit int) (interface{}, int, error) {
+			tracks, err := loadRecommendedTracks(h.db, h.userID)
+			if err != nil {
+				return nil, 0, err
+			}
+			if len(tracks) > limit {
+				tracks = tracks[:limit]
+			}
+			h.downloads.markDownloaded(tracks)
+			if err := loadTrackArtists(h.db, tracks); err != nil {
+				return nil, 0, err
+			}
+			return tracks, len(tracks), nil
+		},
+	},
+	{
+		HomeSection: HomeSection{ID: "newsletters", Title: "What's new", Type: "newsletter", Layout: "cards", Href: "/newsletters"},
+		limit:       5,
+		load: func(h *homeBuilder, limit int) (interface{}, int, error) {
+			items, err := loadNewsletters(h.db, limit)
+			return items, len(items), err
+		},
+	},
+	{
+		HomeSection: HomeSection{ID: "jump_back_in", Title: "Jump back in", Type: "recent", Layout: "carousel"},
+		limit:       10,
+		load: func(h *homeBuilder, limit int) (interface{}, int, error) {
+			items, err := loadRecentItems(h.db, h.userID, time.Now().Add(-jumpBackInAge), limit, h.shown)
+			return items, len(items), err
+		},
+	},
+	{
+		HomeSection: HomeSection{ID: "new_releases", Title: "New releases", Type: "album", Layout: "carousel", Href: "/browse/new-releases"},
+		limit:       10,
+		load: func(h *homeBuilder, limit int) (interface{}, int, error) {
+			var albums []models.Album
+			if err := newReleases(h.db).Preload("Artist").Limit(limit).Find(&albums).Error; err != nil {
+				return nil, 0, err
+			}
+			items := make([]AlbumResponse, len(albums))
+			for i, a := range albums {
+				items[i] = newAlbumResponse(a)
+			}
+			return items, len(items), nil
+		},
+	},
+}
+
+// homeBuilder carries what sections share while the home screen is built.
+type homeBuilder struct {
+	db        *gorm.DB
+	userID    int
+	downloads downloadSet
+	shown     map[string]bool // recent items already on screen
+}
+
+// GET /home?limit=10
+// Sections come back in layout order; empty ones are left out. ?limit=
+// caps every section below its own default.
+func GetHome(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		maxItems := 0
+		if raw := c.Query("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 50 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+				return
+			}
+			maxItems = n
+		}
+
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		h := &homeBuilder{db: db, userID: userID, downloads: downloads, shown: make(map[string]bool)}
+
+		resp := HomeResponse{Sections: make([]HomeSection, 0, len(homeLayout))}
+		for _, s := range homeLayout {
+			limit := s.limit
+			if maxItems > 0 && maxItems < limit {
+				limit = maxItems
+			}
+			items, n, err := s.load(h, limit)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load home section " + s.ID})
+				return
+			}
+			if n == 0 {
+				continue
+			}
+			section := s.HomeSection
+			section.Items = items
+			resp.Sections = append(resp.Sections, section)
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
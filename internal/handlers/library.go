@@ -1,35 +1,435 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// libraryFilters maps the ?filter= chip values to LibraryItem types.
+// "downloaded" is handled separately since it cuts across every type.
+var libraryFilters = map[string]string{
+	"playlists": "playlist",
+	"albums":    "album",
+	"podcasts":  "podcast",
+	"artists":   "artist",
+}
+
+// librarySorts lists the accepted ?sort= values; the first one is the default.
+var librarySorts = []string{"recents", "recently_added", "alphabetical", "creator"}
+
+// GetLibraryData handles GET /library?filter=albums&sort=alphabetical
+// and returns every saved item as one ordered list. Pinned items always
+// come first, in the order they were pinned. Without a filter (or with
+// filter=playlists) playlists are nested under their folders; other
+// filters return a flat list.
 func GetLibraryData(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var playlists []models.Playlist
-		var albums []models.Album
-		var podcasts []models.Podcast
-
-		db.Find(&playlists)
-		db.Find(&albums)
-		db.Find(&podcasts)
-
-		// Default cover image for any album missing one
-		/*const defaultCover = "/media/album-art.jpg"
-		for i := range albums {
-			if albums[i].Cover == "" {
-				albums[i].Cover = defaultCover
+		const userID = 1 // TODO: replace with real auth
+
+		filter := strings.ToLower(c.Query("filter"))
+		if _, ok := libraryFilters[filter]; !ok && filter != "" && filter != "downloaded" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown filter"})
+			return
+		}
+		sortBy := strings.ToLower(c.DefaultQuery("sort", librarySorts[0]))
+		if !containsString(librarySorts, sortBy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown sort"})
+			return
+		}
+
+		items, err := loadLibraryItems(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load library"})
+			return
+		}
+
+		// apply the filter chip
+		nested := filter == "" || filter == "playlists"
+		filtered := items[:0]
+		for _, it := range items {
+			switch {
+			case it.Type == "folder":
+				if !nested {
+					continue
+				}
+			case filter == "":
+			case filter == "downloaded" && !it.Downloaded:
+				continue
+			case filter != "downloaded" && libraryFilters[filter] != it.Type:
+				continue
 			}
-		}*/
+			filtered = append(filtered, it)
+		}
+
+		if nested {
+			filtered = buildLibraryTree(filtered, nil)
+		}
+		sortLibraryItems(filtered, sortBy)
 
 		c.JSON(http.StatusOK, models.LibraryData{
-			Playlists: playlists,
-			Albums:    albums,
-			Podcasts:  podcasts,
+			Filter: filter,
+			Sort:   sortBy,
+			Items:  filtered,
+		})
+	}
+}
+
+// PUT /library/:type/:id/pin
+func PinLibraryItem(db *gorm.DB) gin.HandlerFunc {
+	return setLibraryPin(db, true)
+}
+
+// DELETE /library/:type/:id/pin
+func UnpinLibraryItem(db *gorm.DB) gin.HandlerFunc {
+	return setLibraryPin(db, false)
+}
+
+func setLibraryPin(db *gorm.DB, pinned bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		itemType := c.Param("type")
+		itemID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item ID"})
+			return
+		}
+
+		title, err := libraryItemTitle(db, userID, itemType, itemID)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// pins live on the library entry, so pinning creates one if the item
+		// has none yet; unpinning never adds an item to the library
+		var entry models.LibraryEntry
+		q := db.Where(models.LibraryEntry{UserID: userID, Type: itemType, ReferenceID: strconv.Itoa(itemID)})
+		if pinned {
+			err = q.Attrs(models.LibraryEntry{Title: title}).FirstOrCreate(&entry).Error
+		} else {
+			err = q.First(&entry).Error
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "item is not in your library"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load library entry"})
+			return
+		}
+
+		var pinnedAt *time.Time
+		if pinned {
+			now := time.Now()
+			pinnedAt = &now
+		}
+		if err := db.Model(&entry).
+			Select("pinned", "pinned_at").
+			Updates(models.LibraryEntry{Pinned: pinned, PinnedAt: pinnedAt}).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update pin"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// libraryItemTitle checks that the referenced object exists and returns its
+// title. Folders only count when they belong to the user.
+func libraryItemTitle(db *gorm.DB, userID int, itemType string, id int) (string, error) {
+	switch itemType {
+	case "playlist":
+		var p models.Playlist
+		err := db.First(&p, "id = ?", id).Error
+		return p.Title, err
+	case "album":
+		var a models.Album
+		err := db.First(&a, "album_id = ?", id).Error
+		return a.Title, err
+	case "podcast":
+		var p models.Podcast
+		err := db.First(&p, "id = ?", id).Error
+		return p.Title, err
+	case "artist":
+		var a models.Artist
+		err := db.First(&a, "artist_id = ?", id).Error
+		return a.Name, err
+	case "folder":
+		var f models.Folder
+		err := db.First(&f, "id = ? AND user_id = ?", id, userID).Error
+		return f.Name, err
+	}
+	return "", fmt.Errorf("unknown library item type %q", itemType)
+}
+
+// loadLibraryItems gathers playlists, albums, podcasts and saved artists
+// into LibraryItems, decorated with pin state, added and last-played times
+// and whether they are downloaded on the given device.
+func loadLibraryItems(db *gorm.DB, userID int, device string) ([]models.LibraryItem, error) {
+	var playlists []models.Playlist
+	var albums []models.Album
+	var podcasts []models.Podcast
+	var folders []models.Folder
+	var entries []models.LibraryEntry
+
+	if err := db.Where("user_id = ?", userID).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	// playlists show up when the user made them or saved them
+	var saved []int
+	for _, e := range entries {
+		if id, err := strconv.Atoi(e.ReferenceID); err == nil && e.Type == "playlist" {
+			saved = append(saved, id)
+		}
+	}
+	if err := db.Preload("Owner").Where("user_id = ? OR id IN ?", userID, saved).Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Preload("Artist").Find(&albums).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&podcasts).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]models.LibraryEntry, len(entries))
+	for _, e := range entries {
+		byKey[e.Type+"-"+e.ReferenceID] = e
+	}
+
+	items := make([]models.LibraryItem, 0, len(playlists)+len(albums)+len(podcasts))
+	for _, p := range playlists {
+		// folders belong to the playlist's owner; saved playlists of other
+		// users sit at the root
+		var folderID *int
+		if p.UserID == userID {
+			folderID = p.FolderID
+		}
+		items = append(items, models.LibraryItem{
+			Type:     "playlist",
+			ID:       p.ID,
+			Title:    p.Title,
+			Subtitle: "Playlist • " + p.Owner.Name,
+			Cover:    p.Cover,
+			Creator:  p.Owner.Name,
+			AddedAt:  p.LastUpdated,
+			FolderID: folderID,
+		})
+	}
+	for _, f := range folders {
+		items = append(items, models.LibraryItem{
+			Type:     "folder",
+			ID:       f.ID,
+			Title:    f.Name,
+			Subtitle: "Folder",
+			AddedAt:  f.CreatedAt,
+			FolderID: f.ParentID,
 		})
 	}
+	for _, a := range albums {
+		items = append(items, models.LibraryItem{
+			Type:     "album",
+			ID:       a.AlbumId,
+			Title:    a.Title,
+			Subtitle: "Album • " + a.Artist.Name,
+			Cover:    a.Cover,
+			Creator:  a.Artist.Name,
+		})
+	}
+	for _, p := range podcasts {
+		var hosts []string
+		if len(p.Hosts) > 0 {
+			_ = json.Unmarshal(p.Hosts, &hosts)
+		}
+		items = append(items, models.LibraryItem{
+			Type:     "podcast",
+			ID:       p.ID,
+			Title:    p.Title,
+			Subtitle: "Podcast • " + strings.Join(hosts, ", "),
+			Cover:    p.Cover,
+			Creator:  strings.Join(hosts, ", "),
+		})
+	}
+
+	// artists only show up once the user has saved them
+	for _, e := range entries {
+		if e.Type != "artist" {
+			continue
+		}
+		id, err := strconv.Atoi(e.ReferenceID)
+		if err != nil {
+			continue
+		}
+		var a models.Artist
+		if err := db.First(&a, "artist_id = ?", id).Error; err != nil {
+			continue
+		}
+		items = append(items, models.LibraryItem{
+			Type:     "artist",
+			ID:       a.ArtistId,
+			Title:    a.Name,
+			Subtitle: "Artist",
+			Cover:    a.Image,
+			Creator:  a.Name,
+		})
+	}
+
+	lastPlayed, err := loadLastPlayed(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := loadDownloads(db, userID, device)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		key := fmt.Sprintf("%s-%d", items[i].Type, items[i].ID)
+		if e, ok := byKey[key]; ok {
+			items[i].Pinned = e.Pinned
+			items[i].PinnedAt = e.PinnedAt
+			if items[i].AddedAt.IsZero() {
+				items[i].AddedAt = e.CreatedAt
+			}
+		}
+		if t, ok := lastPlayed[key]; ok {
+			t := t
+			items[i].LastPlayedAt = &t
+		}
+		switch items[i].Type {
+		case "playlist":
+			items[i].Downloaded = downloads.playlists[items[i].ID]
+		case "album":
+			items[i].Downloaded = downloads.albums[items[i].ID]
+		case "podcast":
+			items[i].Downloaded = downloads.showHasDownloads(items[i].ID)
+		}
+	}
+	return items, nil
+}
+
+// loadLastPlayed returns the latest play time per "type-id" key. Plays that
+// came from a context (e.g. a playlist) count towards that context.
+func loadLastPlayed(db *gorm.DB, userID int) (map[string]time.Time, error) {
+	var recs []models.RecentPlay
+	if err := db.
+		Where("user_id = ?", userID).
+		Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Time)
+	for _, r := range recs {
+		id := r.ReferenceID
+		if r.Type != "track" && r.OriginID != 0 {
+			id = r.OriginID
+		}
+		key := fmt.Sprintf("%s-%d", r.Type, id)
+		if r.PlayedAt.After(out[key]) {
+			out[key] = r.PlayedAt
+		}
+	}
+	return out, nil
+}
+
+// buildLibraryTree returns the items whose folder is parentID, with each
+// folder's Children filled in recursively. A folder counts as last played
+// when any of its descendants was.
+func buildLibraryTree(items []models.LibraryItem, parentID *int) []models.LibraryItem {
+	out := make([]models.LibraryItem, 0)
+	for _, it := range items {
+		if !sameFolder(it.FolderID, parentID) {
+			continue
+		}
+		if it.Type == "folder" {
+			id := it.ID
+			it.Children = buildLibraryTree(items, &id)
+			for _, ch := range it.Children {
+				if ch.LastPlayedAt != nil && (it.LastPlayedAt == nil || ch.LastPlayedAt.After(*it.LastPlayedAt)) {
+					it.LastPlayedAt = ch.LastPlayedAt
+				}
+			}
+			it.Subtitle = fmt.Sprintf("Folder • %d items", len(it.Children))
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+func sameFolder(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// sortLibraryItems orders pinned items first and the rest by sortBy,
+// recursing into folders.
+func sortLibraryItems(items []models.LibraryItem, sortBy string) {
+	for i := range items {
+		if len(items[i].Children) > 0 {
+			sortLibraryItems(items[i].Children, sortBy)
+		}
+	}
+
+	less := func(a, b models.LibraryItem) bool {
+		switch sortBy {
+		case "recently_added":
+			return a.AddedAt.After(b.AddedAt)
+		case "alphabetical":
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		case "creator":
+			ca, cb := strings.ToLower(a.Creator), strings.ToLower(b.Creator)
+			if ca != cb {
+				return ca < cb
+			}
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		default: // recents
+			var ta, tb time.Time
+			if a.LastPlayedAt != nil {
+				ta = *a.LastPlayedAt
+			}
+			if b.LastPlayedAt != nil {
+				tb = *b.LastPlayedAt
+			}
+			if !ta.Equal(tb) {
+				return ta.After(tb)
+			}
+			return a.AddedAt.After(b.AddedAt)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Pinned != items[j].Pinned {
+			return items[i].Pinned
+		}
+		if items[i].Pinned && items[i].PinnedAt != nil && items[j].PinnedAt != nil {
+			return items[i].PinnedAt.Before(*items[j].PinnedAt)
+		}
+		return less(items[i], items[j])
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LyricsResponse is the payload for GET /tracks/:id/lyrics
+type LyricsResponse struct {
+	TrackID  int                `json:"track_id"`
+	Synced   bool               `json:"synced"` // false: start_ms is 0 on every line
+	Language string             `json:"language,omitempty"`
+	Source   string             `json:"source"` // "lrc_file" | "defaults"
+	Lines    []models.LyricLine `json:"lines"`
+}
+
+// GET /tracks/:id/lyrics
+// An LRC file next to the track's audio wins; otherwise the lyrics seeded
+// from defaults.json, line-synced when they have LRC, plain text if not.
+func GetTrackLyrics(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trackID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid track ID"})
+			return
+		}
+		var song models.Song
+		if err := db.First(&song, "id = ?", trackID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+			return
+		}
+
+		resp := LyricsResponse{TrackID: song.ID}
+		var stored models.Lyrics
+		err = db.First(&stored, "song_id = ?", song.ID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load lyrics"})
+			return
+		}
+		resp.Language = stored.Language
+
+		lrc, err := readLRCFile(song)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot read lyrics file"})
+			return
+		}
+		switch {
+		case lrc != "":
+			resp.Source, resp.Synced = "lrc_file", true
+		case stored.LRC != "":
+			lrc = stored.LRC
+			resp.Source, resp.Synced = "defaults", true
+		case stored.Text != "":
+			resp.Source = "defaults"
+			resp.Lines = unsyncedLyrics(stored.Text)
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": "lyrics not found"})
+			return
+		}
+		if resp.Synced {
+			if resp.Lines, err = models.ParseLRC(lrc); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid lyrics: " + err.Error()})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// lrcPaths lists where a song's LRC file may sit: <id>.lrc in the media
+// directory, and, when the song has audio of its own, the audio file's
+// name with an .lrc extension.
+func lrcPaths(song models.Song) []string {
+	paths := []string{filepath.Join("media", strconv.Itoa(song.ID)+".lrc")}
+	if strings.HasPrefix(song.AudioURL, "/media/") {
+		audio := strings.TrimPrefix(song.AudioURL, "/")
+		paths = append(paths, filepath.FromSlash(strings.TrimSuffix(audio, path.Ext(audio))+".lrc"))
+	}
+	return paths
+}
+
+// readLRCFile returns the contents of the song's LRC file, or "" if it has
+// none.
+func readLRCFile(song models.Song) (string, error) {
+	for _, p := range lrcPaths(song) {
+		b, err := ioutil.ReadFile(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	return "", nil
+}
+
+// unsyncedLyrics splits plain lyrics into lines without timing, keeping
+// blank lines between verses out.
+func unsyncedLyrics(text string) []models.LyricLine {
+	lines := []models.LyricLine{}
+	for _, l := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, models.LyricLine{Text: l})
+		}
+	}
+	return lines
+}
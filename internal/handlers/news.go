@@ -24,50 +24,60 @@ type NewsletterResponse struct {
 // Frontend calls GET /newsletters :contentReference[oaicite:3]{index=3}.
 func GetNewsletters(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var nl []models.Newsletter
-		if err := db.Find(&nl).Error; err != nil {
+		resp, err := loadNewsletters(db, -1)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load newsletters"})
 			return
 		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
 
-		resp := make([]NewsletterResponse, len(nl))
-		for i, n := range nl {
-			// pick the correct cover based on the newsletter type
-			var cover string
-			switch n.Type {
-			case "ALBUM":
-				var a models.Album
-				if err := db.First(&a, n.ItemID).Error; err == nil {
-					cover = a.Cover
-				}
-			case "PODCAST":
-				var p models.Podcast
-				if err := db.First(&p, n.ItemID).Error; err == nil {
-					cover = p.Cover
-				}
-			case "ARTIST":
-				var ar models.Artist
-				if err := db.First(&ar, n.ItemID).Error; err == nil {
-					// assuming Artist has an Image or Cover field
-					cover = ar.Image
-				}
-			default:
-				// fallback placeholder
-				cover = "/media/default-newsletter.jpg"
-			}
+// loadNewsletters returns up to limit newsletters (-1 = all) with their
+// cover images resolved.
+func loadNewsletters(db *gorm.DB, limit int) ([]NewsletterResponse, error) {
+	var nl []models.Newsletter
+	if err := db.Limit(limit).Find(&nl).Error; err != nil {
+		return nil, err
+	}
 
-			resp[i] = NewsletterResponse{
-				ID:      n.ID,
-				Title:   n.Title,
-				Content: n.Content,
-				Date:    n.Date,
-				Type:    n.Type,
-				ItemID:  n.ItemID,
-				Image:   cover,
+	resp := make([]NewsletterResponse, len(nl))
+	for i, n := range nl {
+		// pick the correct cover based on the newsletter type
+		var cover string
+		switch n.Type {
+		case "ALBUM":
+			var a models.Album
+			if err := db.First(&a, n.ItemID).Error; err == nil {
+				cover = a.Cover
 			}
+		case "PODCAST":
+			var p models.Podcast
+			if err := db.First(&p, n.ItemID).Error; err == nil {
+				cover = p.Cover
+			}
+		case "ARTIST":
+			var ar models.Artist
+			if err := db.First(&ar, n.ItemID).Error; err == nil {
+				// assuming Artist has an Image or Cover field
+				cover = ar.Image
+			}
+		default:
+			// fallback placeholder
+			cover = "/media/default-newsletter.jpg"
+		}
+
+		resp[i] = NewsletterResponse{
+			ID:      n.ID,
+			Title:   n.Title,
+			Content: n.Content,
+			Date:    n.Date,
+			Type:    n.Type,
+			ItemID:  n.ItemID,
+			Image:   cover,
 		}
-		c.JSON(http.StatusOK, resp)
 	}
+	return resp, nil
 }
 
 // GetAllPlaylists returns every playlist in your DB
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PagingResponse mirrors Spotify's paging object. Next and Previous are
+// the request URL with offset moved one page forward or back.
+type PagingResponse struct {
+	Items    interface{} `json:"items"`
+	Total    int         `json:"total"`
+	Limit    int         `json:"limit"`
+	Offset   int         `json:"offset"`
+	Next     string      `json:"next"`
+	Previous string      `json:"previous"`
+}
+
+// parsePaging reads ?limit= and ?offset=, writing a 400 when either is
+// out of range.
+func parsePaging(c *gin.Context, defaultLimit, maxLimit int) (limit, offset int, ok bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+		return 0, 0, false
+	}
+	offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// pageBounds clamps [offset, offset+limit) to a slice of length total.
+func pageBounds(total, limit, offset int) (start, end int) {
+	start = offset
+	if start > total {
+		start = total
+	}
+	end = start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// newPagingResponse wraps one page of items; total is the size of the
+// whole result set.
+func newPagingResponse(c *gin.Context, items interface{}, total, limit, offset int) PagingResponse {
+	resp := PagingResponse{
+		Items:  items,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	if offset+limit < total {
+		resp.Next = pageURL(c, limit, offset+limit)
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		resp.Previous = pageURL(c, limit, prev)
+	}
+	return resp
+}
+
+func pageURL(c *gin.Context, limit, offset int) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
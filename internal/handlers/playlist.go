@@ -6,6 +6,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"net/http"
+	"sort"
 	"spotify-mock-api/internal/models"
 	"strconv"
 	"time"
@@ -85,9 +86,33 @@ func GetRecentPlayedByUser(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// sortPlaylistSongs puts a playlist's preloaded songs in the order of
+// their position in playlist_songs.
+func sortPlaylistSongs(db *gorm.DB, playlistID int, songs []models.Song) error {
+	var order []int
+	if err := db.
+		Model(&models.PlaylistSong{}).
+		Where("playlist_id = ?", playlistID).
+		Order("position, rowid").
+		Pluck("song_id", &order).
+		Error; err != nil {
+		return err
+	}
+	rank := make(map[int]int, len(order))
+	for i, id := range order {
+		rank[id] = i
+	}
+	sort.SliceStable(songs, func(i, j int) bool {
+		return rank[songs[i].ID] < rank[songs[j].ID]
+	})
+	return nil
+}
+
 // GetPlaylistDetail loads one playlist and returns its full detail
 func GetPlaylistDetail(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
 		playlistID := c.Param("id")
 
 		// 1) Load playlist, its owner, and its tracks (+ artists + cover fields)
@@ -104,6 +129,10 @@ func GetPlaylistDetail(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, gin.H{"error": "playlist not found"})
 			return
 		}
+		if err := sortPlaylistSongs(db, pl.ID, pl.Songs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track order"})
+			return
+		}
 
 		// 2) Build the slice of TrackResponse
 		tracks := make([]models.TrackResponse, len(pl.Songs))
@@ -113,15 +142,24 @@ func GetPlaylistDetail(db *gorm.DB) gin.HandlerFunc {
 			totalSec += t.Duration
 
 			tracks[i] = models.TrackResponse{
-				ID:         t.ID,
-				Title:      t.Title,
-				Artist:     t.Artist.Name,
-				AlbumArt:   t.Album.Cover,
-				Downloaded: false,
-				Duration:   t.Duration,
-				Album:      t.Album.Title,
+				ID:       t.ID,
+				Title:    t.Title,
+				Artist:   t.Artist.Name,
+				AlbumArt: t.Album.Cover,
+				Duration: t.Duration,
+				Album:    t.Album.Title,
 			}
 		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
 
 		h := totalSec / 3600
 		m := (totalSec % 3600) / 60
@@ -158,10 +196,21 @@ func AddTrackToPlaylist(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// create join record
+		// create join record, after the playlist's last track
+		var last int
+		if err := db.
+			Model(&models.PlaylistSong{}).
+			Where("playlist_id = ?", plID).
+			Select("COALESCE(MAX(position), 0)").
+			Scan(&last).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not add track"})
+			return
+		}
 		entry := models.PlaylistSong{
 			PlaylistID: plID,
 			SongID:     body.TrackID,
+			Position:   last + 1,
 		}
 		// ON CONFLICT DO NOTHING → no more UNIQUE violations
 		if err := db.
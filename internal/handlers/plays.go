@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// playContextTypes are the objects a play can be started from.
+var playContextTypes = []string{"album", "artist", "playlist", "podcast"}
+
+type playContextRequest struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+}
+
+type reportPlayRequest struct {
+	TrackID     int                 `json:"track_id"`
+	EpisodeID   int                 `json:"episode_id"`
+	ShowID      int                 `json:"show_id"` // optional with episode_id: checked against its show
+	Context     *playContextRequest `json:"context"`
+	MsPlayed    int                 `json:"ms_played"`
+	PositionMs  *int                `json:"position_ms"` // episodes: where playback stopped, for the resume point
+	StartedAt   *time.Time          `json:"started_at"`
+	ReasonStart string              `json:"reason_start"`
+	ReasonEnd   string              `json:"reason_end"`
+	Skipped     bool                `json:"skipped"`
+}
+
+// POST /me/plays
+// Body: { "track_id": 5, "context": { "type": "playlist", "id": 2 },
+//
+//	"ms_played": 183000, "started_at": "2025-05-12T10:00:00Z",
+//	"reason_start": "clickrow", "reason_end": "trackdone", "skipped": false }
+//
+// Records one finished (or abandoned) playback. Episodes are reported with
+// "episode_id" instead of "track_id", and move the episode's resume point.
+func ReportPlay(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		var body reportPlayRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if (body.TrackID == 0) == (body.EpisodeID == 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of track_id or episode_id is required"})
+			return
+		}
+		if body.MsPlayed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ms_played cannot be negative"})
+			return
+		}
+		if body.PositionMs != nil && *body.PositionMs < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "position_ms cannot be negative"})
+			return
+		}
+
+		play := models.RecentPlay{
+			UserID:      userID,
+			Type:        "track",
+			ItemType:    "track",
+			ReferenceID: body.TrackID,
+			MsPlayed:    body.MsPlayed,
+			StartedAt:   body.StartedAt,
+			ReasonStart: body.ReasonStart,
+			ReasonEnd:   body.ReasonEnd,
+			Skipped:     body.Skipped,
+		}
+
+		var ep models.PodcastEpisode
+		if body.TrackID != 0 {
+			var count int64
+			db.Model(&models.Song{}).Where("id = ?", body.TrackID).Count(&count)
+			if count == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+				return
+			}
+		} else {
+			if err := db.First(&ep, "id = ?", body.EpisodeID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "episode not found"})
+				return
+			}
+			if body.ShowID != 0 && body.ShowID != ep.PodcastID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "episode is not in that show"})
+				return
+			}
+			// an episode always plays in the context of its show
+			play.ItemType = "episode"
+			play.ReferenceID = body.EpisodeID
+			play.Type = "podcast"
+			play.OriginID = ep.PodcastID
+		}
+
+		if body.Context != nil && body.TrackID != 0 {
+			if !containsString(playContextTypes, body.Context.Type) || body.Context.ID == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "context needs a type (album, artist, playlist, podcast) and an id"})
+				return
+			}
+			play.Type = body.Context.Type
+			play.OriginID = body.Context.ID
+		}
+
+		// played_at is when playback ended; stored in local time like the
+		// autoCreateTime default, since sqlite compares the two as text
+		if body.StartedAt != nil {
+			play.PlayedAt = body.StartedAt.Add(time.Duration(body.MsPlayed) * time.Millisecond).In(time.Local)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&play).Error; err != nil {
+				return err
+			}
+			if body.EpisodeID != 0 {
+				return advanceResumePoint(tx, userID, ep, body)
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not record play"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
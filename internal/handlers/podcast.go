@@ -5,29 +5,130 @@ import (
 	"net/http"
 	"spotify-mock-api/internal/models"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func podcastEpisodesToTrackResponses(episodes []models.PodcastEpisode, podcast models.Podcast) []models.TrackResponse {
-	responses := make([]models.TrackResponse, len(episodes))
+// EpisodeResponse is an episode as returned by GET /episodes/:id and
+// GET /shows/:id/episodes
+type EpisodeResponse struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Duration    int         `json:"duration"` // in seconds
+	AudioURL    string      `json:"audio_url"`
+	ReleaseDate string      `json:"release_date"` // YYYY-MM-DD
+	Explicit    bool        `json:"explicit"`
+	Downloaded  bool        `json:"downloaded"`
+	ResumePoint ResumePoint `json:"resume_point"`
+	Show        ShowSummary `json:"show"`
+}
+
+// ShowResponse is the payload for GET /podcasts/:id
+type ShowResponse struct {
+	ID            int            `json:"id"`
+	Title         string         `json:"title"`
+	Cover         string         `json:"cover"`
+	Publisher     string         `json:"publisher"` // the feed's author, else the hosts
+	Hosts         []string       `json:"hosts"`
+	Description   string         `json:"description"`
+	TotalEpisodes int            `json:"total_episodes"`
+	Explicit      bool           `json:"explicit"`
+	Languages     []string       `json:"languages"` // e.g. ["en-us"]
+	Episodes      PagingResponse `json:"episodes"`  // of EpisodeResponse, newest first
+}
+
+// ShowSummary names the show an episode belongs to
+type ShowSummary struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Cover string `json:"cover"`
+}
+
+// episodeAudioURL is where an episode's audio is served from; seeded
+// episodes may have a relative path or none.
+func episodeAudioURL(ep models.PodcastEpisode) string {
+	switch {
+	case ep.AudioURL == "":
+		return "/media/song.mp3"
+	case strings.HasPrefix(ep.AudioURL, "media/"):
+		return "/" + ep.AudioURL
+	}
+	return ep.AudioURL
+}
+
+func newEpisodeResponse(ep models.PodcastEpisode, podcast models.Podcast, downloads downloadSet, points map[int]ResumePoint) EpisodeResponse {
+	return EpisodeResponse{
+		ID:          ep.ID,
+		Title:       ep.Title,
+		Description: ep.Description,
+		Duration:    ep.Duration,
+		AudioURL:    episodeAudioURL(ep),
+		ReleaseDate: ep.ReleaseDate.Format("2006-01-02"),
+		Explicit:    ep.Explicit,
+		Downloaded:  downloads.episodes[ep.ID],
+		ResumePoint: points[ep.ID],
+		Show:        ShowSummary{ID: podcast.ID, Title: podcast.Title, Cover: podcast.Cover},
+	}
+}
+
+func episodeIDsOf(episodes []models.PodcastEpisode) []int {
+	ids := make([]int, len(episodes))
 	for i, ep := range episodes {
-		responses[i] = models.TrackResponse{
-			ID:       ep.ID,
-			Title:    ep.Title,
-			Duration: ep.Duration,
-			AudioURL: ep.AudioURL,
-			Artist:   "",
-			Album:    "",
-			AlbumArt: podcast.Cover,
-		}
+		ids[i] = ep.ID
+	}
+	return ids
+}
+
+// podcastHosts reads a show's Hosts JSON.
+func podcastHosts(p models.Podcast) []string {
+	hosts := []string{}
+	if len(p.Hosts) > 0 {
+		_ = json.Unmarshal(p.Hosts, &hosts)
+	}
+	return hosts
+}
+
+// episodePage loads one page of a show's episodes, newest first, as the
+// user sees them on this device.
+func episodePage(db *gorm.DB, c *gin.Context, userID int, podcast models.Podcast, limit, offset int) (PagingResponse, error) {
+	q := db.Model(&models.PodcastEpisode{}).Where("podcast_id = ?", podcast.ID)
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return PagingResponse{}, err
+	}
+	var episodes []models.PodcastEpisode
+	if err := q.
+		Order("release_date DESC, number DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&episodes).
+		Error; err != nil {
+		return PagingResponse{}, err
+	}
+
+	downloads, err := loadDownloads(db, userID, deviceID(c))
+	if err != nil {
+		return PagingResponse{}, err
 	}
-	return responses
+	points, err := loadResumePoints(db, userID, episodeIDsOf(episodes))
+	if err != nil {
+		return PagingResponse{}, err
+	}
+	items := make([]EpisodeResponse, len(episodes))
+	for i, ep := range episodes {
+		items[i] = newEpisodeResponse(ep, podcast, downloads, points)
+	}
+	return newPagingResponse(c, items, int(total), limit, offset), nil
 }
 
+// GET /podcasts/:id?limit=20&offset=0
+// The show with one page of its episodes, newest first.
 func GetPodcastDetail(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
 
 		rawID := c.Param("id")
 		podcastID, err := strconv.Atoi(rawID)
@@ -35,33 +136,109 @@ func GetPodcastDetail(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid podcast ID"})
 			return
 		}
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
 
 		var podcast models.Podcast
-		// Here, fixed to podcast with ID=1. To generalize, get id from params
 		if err := db.First(&podcast, podcastID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Podcast not found"})
 			return
 		}
 
-		// Episodes stored as JSON in podcast.Episodes
-		var episodes []models.PodcastEpisode
-		if len(podcast.Episodes) > 0 {
-			if err := json.Unmarshal(podcast.Episodes, &episodes); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode episodes"})
-				return
-			}
+		episodes, err := episodePage(db, c, userID, podcast, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load episodes"})
+			return
 		}
 
-		resp := PlaylistDetailResponse{
-			ID:    podcast.ID,
-			Title: podcast.Title,
-			Cover: podcast.Cover,
-			//OwnerName:  "",
-			//OwnerImage: artist.Image,
-			//Duration:   podcast.,
-			Tracks: podcastEpisodesToTrackResponses(episodes, podcast),
+		hosts := podcastHosts(podcast)
+		publisher := podcast.Publisher
+		if publisher == "" {
+			publisher = strings.Join(hosts, ", ")
+		}
+		languages := []string{}
+		if podcast.Language != "" {
+			languages = append(languages, podcast.Language)
 		}
 
-		c.JSON(http.StatusOK, resp)
+		c.JSON(http.StatusOK, ShowResponse{
+			ID:            podcast.ID,
+			Title:         podcast.Title,
+			Cover:         podcast.Cover,
+			Publisher:     publisher,
+			Hosts:         hosts,
+			Description:   podcast.Description,
+			TotalEpisodes: episodes.Total,
+			Explicit:      podcast.Explicit,
+			Languages:     languages,
+			Episodes:      episodes,
+		})
+	}
+}
+
+// GET /episodes/:id
+func GetEpisode(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		episodeID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid episode ID"})
+			return
+		}
+		var ep models.PodcastEpisode
+		if err := db.First(&ep, "id = ?", episodeID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "episode not found"})
+			return
+		}
+		var podcast models.Podcast
+		if err := db.First(&podcast, ep.PodcastID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load show"})
+			return
+		}
+
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		points, err := loadResumePoints(db, userID, []int{ep.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load resume points"})
+			return
+		}
+		c.JSON(http.StatusOK, newEpisodeResponse(ep, podcast, downloads, points))
+	}
+}
+
+// GET /shows/:id/episodes?limit=20&offset=0
+// Newest first.
+func GetShowEpisodes(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		showID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid show ID"})
+			return
+		}
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+		var podcast models.Podcast
+		if err := db.First(&podcast, showID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "show not found"})
+			return
+		}
+
+		page, err := episodePage(db, c, userID, podcast, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load episodes"})
+			return
+		}
+		c.JSON(http.StatusOK, page)
 	}
 }
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	radioBatchSize = 10
+	// radioMaxLikedSeeds caps how many liked tracks join a station's seeds.
+	radioMaxLikedSeeds = 3
+)
+
+// RadioResponse is the payload for POST /radio and GET /radio/:id/next
+type RadioResponse struct {
+	ID       int                    `json:"id"`
+	Name     string                 `json:"name"`
+	SeedType string                 `json:"seed_type"`
+	SeedID   int                    `json:"seed_id"`
+	Batch    int                    `json:"batch"`
+	Tracks   []models.TrackResponse `json:"tracks"`
+	Next     string                 `json:"next"`
+}
+
+// POST /radio
+// Body: {"seed_type": "track" | "artist" | "album" | "playlist", "seed_id": 3}
+func CreateRadio(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		var req struct {
+			SeedType string `json:"seed_type" binding:"required"`
+			SeedID   int    `json:"seed_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seed_type and seed_id are required"})
+			return
+		}
+		limit, ok := radioLimit(c)
+		if !ok {
+			return
+		}
+
+		station := models.RadioStation{UserID: userID, SeedType: req.SeedType, SeedID: req.SeedID}
+		_, name, err := radioSeeds(db, station)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": req.SeedType + " not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		station.Name = name + " Radio"
+		if err := db.Create(&station).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create station"})
+			return
+		}
+
+		respondRadioBatch(c, db, userID, station, limit, http.StatusCreated)
+	}
+}
+
+// GET /radio/:id/next?limit=10
+func GetRadioNext(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		station, ok := loadRadioStation(c, db, userID)
+		if !ok {
+			return
+		}
+		limit, ok := radioLimit(c)
+		if !ok {
+			return
+		}
+		respondRadioBatch(c, db, userID, station, limit, http.StatusOK)
+	}
+}
+
+// POST /radio/:id/feedback
+// Body: {"track_id": 8, "feedback": "like" | "skip" | ""}
+// Likes pull later batches towards the track, skips push them away from
+// its artist. An empty feedback clears an earlier one.
+func SetRadioFeedback(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		station, ok := loadRadioStation(c, db, userID)
+		if !ok {
+			return
+		}
+		var req struct {
+			TrackID  int    `json:"track_id" binding:"required"`
+			Feedback string `json:"feedback"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "track_id is required"})
+			return
+		}
+		if req.Feedback != "" && req.Feedback != "like" && req.Feedback != "skip" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "feedback must be like, skip or empty"})
+			return
+		}
+
+		var served models.RadioTrack
+		if err := db.
+			Where("station_id = ? AND song_id = ?", station.ID, req.TrackID).
+			Order("id DESC").
+			First(&served).
+			Error; err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "track was not played on this station"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load station"})
+			return
+		}
+		if err := db.Model(&served).Update("feedback", req.Feedback).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save feedback"})
+			return
+		}
+		c.JSON(http.StatusOK, served)
+	}
+}
+
+func radioLimit(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(radioBatchSize)))
+	if err != nil || limit < 1 || limit > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+		return 0, false
+	}
+	return limit, true
+}
+
+// loadRadioStation finds the user's station from the :id param, writing
+// the error response itself on failure.
+func loadRadioStation(c *gin.Context, db *gorm.DB, userID int) (models.RadioStation, bool) {
+	var station models.RadioStation
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid station ID"})
+		return station, false
+	}
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&station).Error; err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return station, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load station"})
+		return station, false
+	}
+	return station, true
+}
+
+// radioSeeds turns a station's seed into recommendation seeds: albums and
+// playlists are seeded with up to five of their tracks, in track order.
+func radioSeeds(db *gorm.DB, station models.RadioStation) (recommendationSeeds, string, error) {
+	var seeds recommendationSeeds
+	switch station.SeedType {
+	case "track":
+		var s models.Song
+		if err := db.First(&s, station.SeedID).Error; err != nil {
+			return seeds, "", err
+		}
+		seeds.Tracks = []int{s.ID}
+		return seeds, s.Title, nil
+	case "artist":
+		var a models.Artist
+		if err := db.First(&a, station.SeedID).Error; err != nil {
+			return seeds, "", err
+		}
+		seeds.Artists = []int{a.ArtistId}
+		return seeds, a.Name, nil
+	case "album":
+		var a models.Album
+		if err := db.First(&a, station.SeedID).Error; err != nil {
+			return seeds, "", err
+		}
+		if err := db.
+			Model(&models.Song{}).
+			Where("album_id = ?", a.AlbumId).
+			Order("disc_number, track_number, id").
+			Limit(maxRecommendationSeeds).
+			Pluck("id", &seeds.Tracks).
+			Error; err != nil {
+			return seeds, "", err
+		}
+		if len(seeds.Tracks) == 0 {
+			seeds.Artists = []int{a.ArtistID}
+		}
+		return seeds, a.Title, nil
+	case "playlist":
+		var p models.Playlist
+		if err := db.First(&p, station.SeedID).Error; err != nil {
+			return seeds, "", err
+		}
+		if err := db.
+			Model(&models.PlaylistSong{}).
+			Where("playlist_id = ?", p.ID).
+			Order("position, rowid").
+			Limit(maxRecommendationSeeds).
+			Pluck("song_id", &seeds.Tracks).
+			Error; err != nil {
+			return seeds, "", err
+		}
+		if len(seeds.Tracks) == 0 {
+			return seeds, "", fmt.Errorf("playlist %d has no tracks to seed a station", p.ID)
+		}
+		return seeds, p.Title, nil
+	}
+	return seeds, "", fmt.Errorf("seed_type must be track, artist, album or playlist")
+}
+
+// radioSeedSongs lists the songs a station is seeded from: the seed track,
+// or every track of the seed album or playlist. Stations never serve them,
+// whether or not they made it into the recommendation seeds.
+func radioSeedSongs(db *gorm.DB, station models.RadioStation) ([]int, error) {
+	var ids []int
+	var err error
+	switch station.SeedType {
+	case "track":
+		ids = []int{station.SeedID}
+	case "album":
+		err = db.Model(&models.Song{}).Where("album_id = ?", station.SeedID).Pluck("id", &ids).Error
+	case "playlist":
+		err = db.Model(&models.PlaylistSong{}).Where("playlist_id = ?", station.SeedID).Pluck("song_id", &ids).Error
+	}
+	return ids, err
+}
+
+// nextRadioBatch picks the station's next songs. Liked tracks join the
+// seeds, skipped tracks cost their artist points, and nothing served
+// before is repeated until the station runs out of matching songs. The
+// station's own songs (see radioSeedSongs) and liked tracks are never served.
+func nextRadioBatch(db *gorm.DB, station models.RadioStation, limit int) ([]models.Song, error) {
+	seeds, _, err := radioSeeds(db, station)
+	if err != nil {
+		return nil, err
+	}
+	own, err := radioSeedSongs(db, station)
+	if err != nil {
+		return nil, err
+	}
+
+	var served []models.RadioTrack
+	if err := db.Where("station_id = ?", station.ID).Order("id DESC").Find(&served).Error; err != nil {
+		return nil, err
+	}
+
+	// lastServed ranks songs by recency: 0 is the most recently served
+	lastServed := make(map[int]int, len(served))
+	skipped := make(map[int]bool)
+	var liked []int
+	for i, t := range served {
+		if _, ok := lastServed[t.SongID]; !ok {
+			lastServed[t.SongID] = i
+		}
+		switch t.Feedback {
+		case "skip":
+			skipped[t.SongID] = true
+		case "like":
+			if len(liked) < radioMaxLikedSeeds && !containsInt(liked, t.SongID) {
+				liked = append(liked, t.SongID)
+			}
+		}
+	}
+
+	// likes take seed slots from the end of the station's own seeds, but
+	// the original seed always keeps at least one
+	if len(liked) > 0 {
+		room := maxRecommendationSeeds - len(liked)
+		for seeds.count() > room && len(seeds.Tracks) > 1 {
+			seeds.Tracks = seeds.Tracks[:len(seeds.Tracks)-1]
+		}
+		for _, id := range liked {
+			if !containsInt(seeds.Tracks, id) {
+				seeds.Tracks = append(seeds.Tracks, id)
+			}
+		}
+	}
+
+	skippedArtists := make(map[int]int)
+	if len(skipped) > 0 {
+		var songs []models.Song
+		if err := db.Where("id IN ?", keysOf(skipped)).Find(&songs).Error; err != nil {
+			return nil, err
+		}
+		for _, s := range songs {
+			skippedArtists[s.ArtistID]++
+		}
+	}
+
+	pick := func(exclude map[int]bool) ([]scoredSong, error) {
+		withOwn := make(map[int]bool, len(exclude)+len(own))
+		for id := range exclude {
+			withOwn[id] = true
+		}
+		for _, id := range own {
+			withOwn[id] = true
+		}
+		scored, _, err := scoreRecommendations(db, seeds, nil, withOwn)
+		if err != nil {
+			return nil, err
+		}
+		for i := range scored {
+			scored[i].Score -= 2 * float64(skippedArtists[scored[i].Song.ArtistID])
+		}
+		sort.SliceStable(scored, func(i, j int) bool {
+			return scored[i].Score > scored[j].Score
+		})
+		return scored, nil
+	}
+
+	played := make(map[int]bool, len(lastServed))
+	for id := range lastServed {
+		played[id] = true
+	}
+	scored, err := pick(played)
+	if err != nil {
+		return nil, err
+	}
+	if len(scored) < limit {
+		// out of fresh songs: replay what was heard longest ago, never
+		// anything that was skipped
+		more, err := pick(skipped)
+		if err != nil {
+			return nil, err
+		}
+		var repeats []scoredSong
+		for _, s := range more {
+			if played[s.Song.ID] {
+				repeats = append(repeats, s)
+			}
+		}
+		sort.SliceStable(repeats, func(i, j int) bool {
+			return lastServed[repeats[i].Song.ID] > lastServed[repeats[j].Song.ID]
+		})
+		scored = append(scored, repeats...)
+	}
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	songs := make([]models.Song, len(scored))
+	for i, s := range scored {
+		songs[i] = s.Song
+	}
+	return songs, nil
+}
+
+// respondRadioBatch serves the station's next batch and records it.
+func respondRadioBatch(c *gin.Context, db *gorm.DB, userID int, station models.RadioStation, limit, status int) {
+	songs, err := nextRadioBatch(db, station, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not build station"})
+		return
+	}
+
+	var batch int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Model(&models.RadioTrack{}).
+			Where("station_id = ?", station.ID).
+			Select("COALESCE(MAX(batch), 0) + 1").
+			Scan(&batch).
+			Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, s := range songs {
+			if err := tx.Create(&models.RadioTrack{
+				StationID: station.ID,
+				SongID:    s.ID,
+				Batch:     batch,
+				ServedAt:  now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&station).Update("updated_at", now).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save station"})
+		return
+	}
+
+	tracks := make([]models.TrackResponse, len(songs))
+	for i, s := range songs {
+		tracks[i] = newTrackResponse(s)
+	}
+	downloads, err := loadDownloads(db, userID, deviceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+		return
+	}
+	downloads.markDownloaded(tracks)
+	if err := loadTrackArtists(db, tracks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+		return
+	}
+
+	c.JSON(status, RadioResponse{
+		ID:       station.ID,
+		Name:     station.Name,
+		SeedType: station.SeedType,
+		SeedID:   station.SeedID,
+		Batch:    batch,
+		Tracks:   tracks,
+		Next:     fmt.Sprintf("/radio/%d/next", station.ID),
+	})
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func keysOf(set map[int]bool) []int {
+	out := make([]int, 0, len(set))
+	for id := range set {
+		out = append(out, id)
+	}
+	sort.Ints(out)
+	return out
+}
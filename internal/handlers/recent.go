@@ -6,6 +6,8 @@ import (
 	"gorm.io/gorm"
 	"net/http"
 	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,84 +23,109 @@ type RecentItemResponse struct {
 
 func GetRecentPlays(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var recs []models.RecentPlay
-		if err := db.
-			Where("user_id = ?", 1).
-			Order("played_at DESC").
-			Limit(50). // Increase limit if you want 20 unique recents
-			Find(&recs).Error; err != nil {
+		out, err := loadRecentItems(db, 1, time.Time{}, 8, nil)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load recents"})
 			return
 		}
+		c.JSON(http.StatusOK, out)
+	}
+}
 
-		out := make([]RecentItemResponse, 0, len(recs))
-		seen := make(map[string]bool)
+// recentItemKey identifies a recent item for de-duplication, e.g. "album-3".
+func recentItemKey(itemType string, id int) string {
+	return fmt.Sprintf("%s-%d", itemType, id)
+}
 
-		for _, r := range recs {
-			// Key by type and ReferenceID to ensure uniqueness
-			key := fmt.Sprintf("%s-%d", r.Type, r.ReferenceID)
-			if seen[key] {
-				continue // skip duplicates
-			}
-			seen[key] = true
+// loadRecentItems returns up to limit distinct items the user played
+// before the given time (zero = now), newest first. Items whose
+// recentItemKey is in skip are left out.
+func loadRecentItems(db *gorm.DB, userID int, before time.Time, limit int, skip map[string]bool) ([]RecentItemResponse, error) {
+	q := db.Where("user_id = ?", userID)
+	if !before.IsZero() {
+		q = q.Where("played_at < ?", before)
+	}
+	var recs []models.RecentPlay
+	if err := q.
+		Order("played_at DESC").
+		Limit(limit * 25). // plenty of rows to find limit unique items
+		Find(&recs).Error; err != nil {
+		return nil, err
+	}
 
-			item := RecentItemResponse{
-				Type:     r.Type,
-				ID:       r.ReferenceID,
-				PlayedAt: r.PlayedAt.Format(time.RFC3339),
-			}
+	out := make([]RecentItemResponse, 0, limit)
+	seen := make(map[string]bool)
 
-			// fetch metadata as before
-			switch r.Type {
-			case "track":
-				var s models.Song
-				if err := db.Preload("Artist").First(&s, "id = ?", r.ReferenceID).Error; err == nil {
-					item.Title = s.Title
-					item.Subtitle = s.Artist.Name
-					item.Cover = "/media/album-art.jpg"
-				}
-			case "artist":
-				var a models.Artist
-				if err := db.First(&a, "artist_id = ?", r.ReferenceID).Error; err == nil {
-					item.Title = a.Name
-				}
-			case "album":
-				var a models.Album
-				if err := db.First(&a, "album_id = ?", r.ReferenceID).Error; err == nil {
-					item.Title = a.Title
-					item.Cover = "/media/album-art.jpg"
-				}
-			case "playlist":
-				var p models.Playlist
-				if err := db.First(&p, "id = ?", r.ReferenceID).Error; err == nil {
-					item.Title = p.Title
-					item.Cover = p.Cover
-					item.Subtitle = fmt.Sprintf("Updated %s", p.LastUpdated.Format("2006-01-02"))
-				}
-			case "podcast":
-				var p models.Podcast
-				if err := db.First(&p, "id = ?", r.ReferenceID).Error; err == nil {
-					item.Title = p.Title
-					item.Cover = p.Cover
-				}
-			}
-			out = append(out, item)
+	for _, r := range recs {
+		// plays from a context (playlist, album…) show up as that context
+		refID := r.ReferenceID
+		if ctx := recentPlayContext(r); ctx != nil {
+			refID = ctx.ID
+		}
 
-			// If you want exactly 8 items, break after collecting 8 uniques
-			if len(out) >= 8 {
-				break
+		// Key by type and ID to ensure uniqueness
+		key := recentItemKey(r.Type, refID)
+		if seen[key] || skip[key] {
+			continue // skip duplicates
+		}
+		seen[key] = true
+
+		item := RecentItemResponse{
+			Type:     r.Type,
+			ID:       refID,
+			PlayedAt: r.PlayedAt.Format(time.RFC3339),
+		}
+
+		// fetch metadata as before
+		switch r.Type {
+		case "track":
+			var s models.Song
+			if err := db.Preload("Artist").First(&s, "id = ?", refID).Error; err == nil {
+				item.Title = s.Title
+				item.Subtitle = s.Artist.Name
+				item.Cover = "/media/album-art.jpg"
+			}
+		case "artist":
+			var a models.Artist
+			if err := db.First(&a, "artist_id = ?", refID).Error; err == nil {
+				item.Title = a.Name
+			}
+		case "album":
+			var a models.Album
+			if err := db.First(&a, "album_id = ?", refID).Error; err == nil {
+				item.Title = a.Title
+				item.Cover = "/media/album-art.jpg"
+			}
+		case "playlist":
+			var p models.Playlist
+			if err := db.First(&p, "id = ?", refID).Error; err == nil {
+				item.Title = p.Title
+				item.Cover = p.Cover
+				item.Subtitle = fmt.Sprintf("Updated %s", p.LastUpdated.Format("2006-01-02"))
+			}
+		case "podcast":
+			var p models.Podcast
+			if err := db.First(&p, "id = ?", refID).Error; err == nil {
+				item.Title = p.Title
+				item.Cover = p.Cover
 			}
 		}
+		out = append(out, item)
 
-		c.JSON(http.StatusOK, out)
+		if len(out) >= limit {
+			break
+		}
 	}
+	return out, nil
 }
 
 func GetRecentTracks(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
 		var recs []models.RecentPlay
 		if err := db.
-			Where("user_id = ?", 1).
+			Where("user_id = ? AND item_type = ?", userID, "track").
 			Order("played_at DESC").
 			Limit(8). // Increase limit if you want 20 unique recents
 			Find(&recs).Error; err != nil {
@@ -117,38 +144,237 @@ func GetRecentTracks(db *gorm.DB) gin.HandlerFunc {
 			}
 			seen[key] = true
 
-			item := RecentItemResponse{
-				Type:     r.Type,
-				ID:       r.ReferenceID,
-				PlayedAt: r.PlayedAt.Format(time.RFC3339),
+			// every play row is a track play; Type only says where it came from
+			var s models.Song
+			if err := db.
+				Preload("Artist").
+				Preload("Album").
+				First(&s, "id = ?", r.ReferenceID).
+				Error; err != nil {
+				continue
+			}
+			out = append(out, newTrackResponse(s))
+
+			// If you want exactly 8 items, break after collecting 8 uniques
+			if len(out) >= 4 {
+				break
+			}
+		}
+
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(out)
+		if err := loadTrackArtists(db, out); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// PlayContext is the object a track was played from, e.g. a playlist.
+type PlayContext struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+	URI  string `json:"uri"`
+}
+
+// PlayHistoryItem mirrors Spotify's play-history object.
+type PlayHistoryItem struct {
+	Track    models.TrackResponse `json:"track"`
+	PlayedAt time.Time            `json:"played_at"`
+	Context  *PlayContext         `json:"context"`
+}
+
+// PlayHistoryCursors point at the oldest and newest play of a page, to pass
+// back as before/after.
+type PlayHistoryCursors struct {
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// RecentlyPlayedResponse is the payload for GET /me/player/recently-played
+type RecentlyPlayedResponse struct {
+	Items   []PlayHistoryItem   `json:"items"`
+	Limit   int                 `json:"limit"`
+	Next    string              `json:"next,omitempty"`
+	Cursors *PlayHistoryCursors `json:"cursors"`
+}
+
+// playCursor identifies a play exactly: its played_at in unix ns and its
+// ID, which breaks ties between plays in the same instant.
+func playCursor(p models.RecentPlay) string {
+	return fmt.Sprintf("%d:%d", p.PlayedAt.UnixNano(), p.ID)
+}
+
+// parsePlayCursor reads a playCursor, or a bare unix-ms timestamp, in which
+// case id is 0.
+func parsePlayCursor(s string) (t time.Time, id uint64, err error) {
+	ts, rawID, exact := strings.Cut(s, ":")
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return t, 0, err
+	}
+	if !exact {
+		return time.UnixMilli(n), 0, nil
+	}
+	id, err = strconv.ParseUint(rawID, 10, 64)
+	return time.Unix(0, n), id, err
+}
+
+// GetRecentlyPlayed handles
+// GET /me/player/recently-played?limit=20&before=1715000000000123456:42&dedupe=true
+// before/after are the cursors of an earlier page (a unix-ms timestamp works
+// too) and cannot be combined. Repeated plays of the same track are kept
+// unless dedupe=true, which keeps the newest of them.
+func GetRecentlyPlayed(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 || limit > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		before, after := c.Query("before"), c.Query("after")
+		if before != "" && after != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only one of before or after may be set"})
+			return
+		}
+		dedupe := c.Query("dedupe") == "true"
+
+		q := db.Where("user_id = ? AND item_type = ?", userID, "track")
+		order := "played_at DESC, id DESC"
+		if before != "" {
+			t, id, err := parsePlayCursor(before)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a cursor from an earlier page"})
+				return
 			}
+			q = q.Where("played_at < ? OR (played_at = ? AND id < ?)", t, t, id)
+		}
+		if after != "" {
+			t, id, err := parsePlayCursor(after)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a cursor from an earlier page"})
+				return
+			}
+			if id == 0 {
+				// a unix-ms cursor: played_at has sub-ms precision, so start
+				// at the next whole ms
+				q = q.Where("played_at >= ?", t.Add(time.Millisecond))
+			} else {
+				q = q.Where("played_at > ? OR (played_at = ? AND id > ?)", t, t, id)
+			}
+			// walk forward from the cursor so the page is contiguous with it
+			order = "played_at ASC, id ASC"
+		}
+		if !dedupe {
+			q = q.Limit(limit)
+		}
 
-			// fetch metadata as before
-			switch r.Type {
-			case "track":
-				var s models.Song
-				if err := db.Preload("Artist").First(&s, "id = ?", r.ReferenceID).Error; err == nil {
-					item.Title = s.Title
-					item.Subtitle = s.Artist.Name
-					item.Cover = "/media/album-art.jpg"
+		var recs []models.RecentPlay
+		if err := q.Order(order).Find(&recs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load recents"})
+			return
+		}
+		if after != "" {
+			if dedupe {
+				// the page ends before the play of a limit+1-th distinct track
+				distinct := make(map[int]bool)
+				for i, r := range recs {
+					if !distinct[r.ReferenceID] && len(distinct) == limit {
+						recs = recs[:i]
+						break
+					}
+					distinct[r.ReferenceID] = true
 				}
 			}
+			// newest first, like every other page, so dedupe keeps the newest
+			for i, j := 0, len(recs)-1; i < j; i, j = i+1, j-1 {
+				recs[i], recs[j] = recs[j], recs[i]
+			}
+		}
+
+		items := make([]PlayHistoryItem, 0, limit)
+		var oldest, newest models.RecentPlay
+		seen := make(map[int]bool)
+		for _, r := range recs {
+			if len(items) >= limit {
+				break
+			}
+			if dedupe && seen[r.ReferenceID] {
+				continue
+			}
+			seen[r.ReferenceID] = true
 
-			out = append(out, models.TrackResponse{
-				ID:         r.ReferenceID,
-				Title:      item.Title,
-				Artist:     item.Subtitle,
-				AlbumArt:   item.Cover,
-				Duration:   169,
-				Downloaded: false,
+			var s models.Song
+			if err := db.
+				Preload("Artist").
+				Preload("Album").
+				First(&s, "id = ?", r.ReferenceID).
+				Error; err != nil {
+				continue // the track is gone, skip its history
+			}
+			if len(items) == 0 {
+				newest = r
+			}
+			oldest = r
+			items = append(items, PlayHistoryItem{
+				Track:    newTrackResponse(s),
+				PlayedAt: r.PlayedAt,
+				Context:  recentPlayContext(r),
 			})
+		}
 
-			// If you want exactly 8 items, break after collecting 8 uniques
-			if len(out) >= 4 {
-				break
+		tracks := make([]models.TrackResponse, len(items))
+		for i := range items {
+			tracks[i] = items[i].Track
+		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+		for i := range items {
+			items[i].Track = tracks[i]
+		}
+
+		resp := RecentlyPlayedResponse{Items: items, Limit: limit}
+		if len(items) > 0 {
+			resp.Cursors = &PlayHistoryCursors{
+				Before: playCursor(oldest),
+				After:  playCursor(newest),
+			}
+			if len(items) == limit {
+				resp.Next = fmt.Sprintf("/me/player/recently-played?limit=%d&before=%s", limit, playCursor(oldest))
+				if dedupe {
+					resp.Next += "&dedupe=true"
+				}
 			}
 		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
 
-		c.JSON(http.StatusOK, out)
+// recentPlayContext returns where a play came from, or nil when the track
+// was played on its own.
+func recentPlayContext(r models.RecentPlay) *PlayContext {
+	if r.Type == "" || r.Type == "track" || r.OriginID == 0 {
+		return nil
+	}
+	return &PlayContext{
+		Type: r.Type,
+		ID:   r.OriginID,
+		URI:  fmt.Sprintf("spotify:%s:%d", r.Type, r.OriginID),
 	}
 }
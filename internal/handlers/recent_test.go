@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"spotify-mock-api/internal/models"
+	"testing"
+	"time"
+)
+
+func TestParsePlayCursor(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   time.Time
+		wantID uint64
+		ok     bool
+	}{
+		{"1715000000000123456:42", time.Unix(0, 1715000000000123456), 42, true},
+		{"1715000000000123456:0", time.Unix(0, 1715000000000123456), 0, true},
+		{"1715000000000", time.UnixMilli(1715000000000), 0, true},
+		{"", time.Time{}, 0, false},
+		{"yesterday", time.Time{}, 0, false},
+		{"1715000000000123456:", time.Time{}, 0, false},
+		{"1715000000000123456:x", time.Time{}, 0, false},
+		{"1715000000000123456:-1", time.Time{}, 0, false},
+		{":42", time.Time{}, 0, false},
+	}
+	for _, tt := range tests {
+		got, id, err := parsePlayCursor(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("parsePlayCursor(%q) error = %v, want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && (!got.Equal(tt.want) || id != tt.wantID) {
+			t.Errorf("parsePlayCursor(%q) = %v, %d; want %v, %d", tt.in, got, id, tt.want, tt.wantID)
+		}
+	}
+}
+
+func TestPlayCursorRoundTrip(t *testing.T) {
+	p := models.RecentPlay{ID: 7, PlayedAt: time.Date(2025, 5, 1, 12, 0, 0, 123456789, time.UTC)}
+	got, id, err := parsePlayCursor(playCursor(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(p.PlayedAt) || id != uint64(p.ID) {
+		t.Errorf("round trip = %v, %d; want %v, %d", got, id, p.PlayedAt, p.ID)
+	}
+}
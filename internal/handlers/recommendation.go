@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"math"
 	"net/http"
+	"sort"
 	"spotify-mock-api/internal/models"
+	"strconv"
 	"strings"
 )
 
@@ -12,16 +18,39 @@ func GetRecommendations(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const userID = 1 // TODO: replace with real auth
 
-		// helper to actually load TrackRec by a WHERE clause
-		loadTracks := func(whereSQL string, args ...interface{}) ([]models.TrackResponse, error) {
-			//conds := strings.Repeat("?", 1) // not used, but placeholder
-			// we'll build our raw query dynamically below...
-			var recs []models.TrackResponse
-			query := `
+		recs, err := loadRecommendedTracks(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load recommendations"})
+			return
+		}
+
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(recs)
+		if err := loadTrackArtists(db, recs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, recs)
+	}
+}
+
+// loadRecommendedTracks picks up to 20 random tracks from the user's recent
+// plays, falling back to their playlists and then the whole catalog.
+func loadRecommendedTracks(db *gorm.DB, userID int) ([]models.TrackResponse, error) {
+	// helper to actually load TrackRec by a WHERE clause
+	loadTracks := func(whereSQL string, args ...interface{}) ([]models.TrackResponse, error) {
+		//conds := strings.Repeat("?", 1) // not used, but placeholder
+		// we'll build our raw query dynamically below...
+		var recs []models.TrackResponse
+		query := `
 				SELECT s.id,
 				       s.title,
 				       a.name   AS artist,
-				       s.genres AS genres,
 				       '/media/song.mp3' as audio_url, -- hardcoded for now
 				       al.cover AS album_art,
 				       al.title AS album,
@@ -35,74 +64,459 @@ func GetRecommendations(db *gorm.DB) gin.HandlerFunc {
 				ORDER BY RANDOM()
 				LIMIT 20
 			`
-			if err := db.Raw(query, args...).Scan(&recs).Error; err != nil {
-				return nil, err
-			}
-
-			return recs, nil
+		if err := db.Raw(query, args...).Scan(&recs).Error; err != nil {
+			return nil, err
+		}
+		if err := fillTrackGenres(db, recs); err != nil {
+			return nil, err
 		}
+		return recs, nil
+	}
 
-		// 1) Try recent plays
-		var recPlays []string
-		if err := db.
-			Raw(`SELECT reference_id 
+	// 1) Try recent plays
+	var recPlays []string
+	if err := db.
+		Raw(`SELECT reference_id 
 			      FROM recent_plays 
-			      WHERE user_id = ? 
+			      WHERE user_id = ? AND item_type = 'track'
 			      ORDER BY played_at DESC 
 			      LIMIT 20`, userID).
-			Scan(&recPlays).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load recents"})
-			return
-		}
+		Scan(&recPlays).Error; err != nil {
+		return nil, err
+	}
 
-		var recs []models.TrackResponse
-		var err error
+	var recs []models.TrackResponse
+	var err error
 
-		if len(recPlays) > 0 {
-			// build WHERE id IN (?, ?, …)
-			placeholders := strings.Repeat("?,", len(recPlays))
-			placeholders = strings.TrimRight(placeholders, ",")
-			where := "s.id IN (" + placeholders + ")"
-			args := make([]interface{}, len(recPlays))
-			for i, id := range recPlays {
-				args[i] = id
-			}
-			recs, err = loadTracks(where, args...)
+	if len(recPlays) > 0 {
+		// build WHERE id IN (?, ?, …)
+		placeholders := strings.Repeat("?,", len(recPlays))
+		placeholders = strings.TrimRight(placeholders, ",")
+		where := "s.id IN (" + placeholders + ")"
+		args := make([]interface{}, len(recPlays))
+		for i, id := range recPlays {
+			args[i] = id
 		}
+		recs, err = loadTracks(where, args...)
+	}
 
-		// 2) Fallback → user playlists
-		if err == nil && len(recs) == 0 {
-			// gather song_ids from ANY of this user’s playlists
-			var songIDs []int
-			if err = db.
-				Raw(`
+	// 2) Fallback → user playlists
+	if err == nil && len(recs) == 0 {
+		// gather song_ids from ANY of this user’s playlists
+		var songIDs []int
+		if err = db.
+			Raw(`
 				  SELECT ps.song_id 
 				  FROM playlist_songs ps
 				  JOIN playlists p ON p.id = ps.playlist_id
 				  WHERE p.user_id = ?
 				`, userID).
-				Scan(&songIDs).Error; err == nil && len(songIDs) > 0 {
-				// convert to []interface{} for query
-				args := make([]interface{}, len(songIDs))
-				for i, id := range songIDs {
-					args[i] = id
+			Scan(&songIDs).Error; err == nil && len(songIDs) > 0 {
+			// convert to []interface{} for query
+			args := make([]interface{}, len(songIDs))
+			for i, id := range songIDs {
+				args[i] = id
+			}
+			placeholders := strings.Repeat("?,", len(args))
+			placeholders = strings.TrimRight(placeholders, ",")
+			where := "s.id IN (" + placeholders + ")"
+			recs, err = loadTracks(where, args...)
+		}
+	}
+
+	// 3) Random fallback
+	if err == nil && len(recs) == 0 {
+		recs, err = loadTracks("1=1") // no WHERE, pure random
+	}
+	return recs, err
+}
+
+// fillTrackGenres sets Genres on each track to a JSON list of its catalog
+// genres, e.g. ["pop","r&b"], sorted; tracks without genres keep none.
+func fillTrackGenres(db *gorm.DB, tracks []models.TrackResponse) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	ids := make([]int, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	var songs []models.Song
+	if err := db.Preload("GenreTags").Where("id IN ?", ids).Find(&songs).Error; err != nil {
+		return err
+	}
+	genres := make(map[int][]string, len(songs))
+	for _, s := range songs {
+		names := songGenres(s)
+		sort.Strings(names)
+		genres[s.ID] = names
+	}
+	for i := range tracks {
+		names := genres[tracks[i].ID]
+		if len(names) == 0 {
+			continue
+		}
+		raw, err := json.Marshal(names)
+		if err != nil {
+			return err
+		}
+		tracks[i].Genres = string(raw)
+	}
+	return nil
+}
+
+// maxRecommendationSeeds is the combined cap on seed_tracks, seed_artists
+// and seed_genres, as in Spotify's API.
+const maxRecommendationSeeds = 5
+
+// RecommendationSeed mirrors Spotify's recommendation seed object.
+type RecommendationSeed struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"` // "track" | "artist" | "genre"
+	Href               string `json:"href,omitempty"`
+	InitialPoolSize    int    `json:"initialPoolSize"`
+	AfterFilteringSize int    `json:"afterFilteringSize"`
+	AfterRelinkingSize int    `json:"afterRelinkingSize"`
+}
+
+// RecommendationsResponse is the payload for GET /recommendations
+type RecommendationsResponse struct {
+	Seeds  []RecommendationSeed   `json:"seeds"`
+	Tracks []models.TrackResponse `json:"tracks"`
+}
+
+// recommendationSeeds are the inputs to scoreRecommendations.
+type recommendationSeeds struct {
+	Tracks  []int
+	Artists []int
+	Genres  []string
+}
+
+func (s recommendationSeeds) count() int {
+	return len(s.Tracks) + len(s.Artists) + len(s.Genres)
+}
+
+// trackAttribute reads one tunable attribute from a song's audio features.
+type trackAttribute struct {
+	value func(f models.AudioFeatures) float64
+	scale float64 // distance from a target that costs one score point
+}
+
+// trackAttributes are the attributes usable as min_/max_/target_ tunables.
+var trackAttributes = map[string]trackAttribute{
+	"acousticness":     {value: func(f models.AudioFeatures) float64 { return f.Acousticness }, scale: 0.2},
+	"danceability":     {value: func(f models.AudioFeatures) float64 { return f.Danceability }, scale: 0.2},
+	"duration_ms":      {value: func(f models.AudioFeatures) float64 { return float64(f.DurationMs) }, scale: 60000},
+	"energy":           {value: func(f models.AudioFeatures) float64 { return f.Energy }, scale: 0.2},
+	"instrumentalness": {value: func(f models.AudioFeatures) float64 { return f.Instrumentalness }, scale: 0.2},
+	"key":              {value: func(f models.AudioFeatures) float64 { return float64(f.Key) }, scale: 2},
+	"liveness":         {value: func(f models.AudioFeatures) float64 { return f.Liveness }, scale: 0.2},
+	"loudness":         {value: func(f models.AudioFeatures) float64 { return f.Loudness }, scale: 3},
+	"mode":             {value: func(f models.AudioFeatures) float64 { return float64(f.Mode) }, scale: 1},
+	"speechiness":      {value: func(f models.AudioFeatures) float64 { return f.Speechiness }, scale: 0.2},
+	"tempo":            {value: func(f models.AudioFeatures) float64 { return f.Tempo }, scale: 20},
+	"time_signature":   {value: func(f models.AudioFeatures) float64 { return float64(f.TimeSignature) }, scale: 1},
+	"valence":          {value: func(f models.AudioFeatures) float64 { return f.Valence }, scale: 0.2},
+}
+
+// tunable holds the min_, max_ and target_ values for one attribute.
+type tunable struct {
+	min, max, target *float64
+}
+
+// parseTunables reads every min_/max_/target_<attribute> query param.
+func parseTunables(c *gin.Context) (map[string]tunable, error) {
+	out := make(map[string]tunable)
+	for key, vals := range c.Request.URL.Query() {
+		var prefix string
+		for _, p := range []string{"min_", "max_", "target_"} {
+			if strings.HasPrefix(key, p) {
+				prefix = p
+			}
+		}
+		if prefix == "" {
+			continue
+		}
+		attr := strings.TrimPrefix(key, prefix)
+		if _, ok := trackAttributes[attr]; !ok {
+			return nil, fmt.Errorf("unsupported tunable attribute %q", attr)
+		}
+		v, err := strconv.ParseFloat(vals[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", key)
+		}
+		t := out[attr]
+		switch prefix {
+		case "min_":
+			t.min = &v
+		case "max_":
+			t.max = &v
+		case "target_":
+			t.target = &v
+		}
+		out[attr] = t
+	}
+	return out, nil
+}
+
+// errUnknownSeed is wrapped by the errors scoreRecommendations returns for
+// seeds that don't exist, which are the caller's fault rather than ours.
+var errUnknownSeed = errors.New("unknown seed")
+
+// scoredSong is a recommendation candidate and how well it matched.
+type scoredSong struct {
+	Song  models.Song
+	Score float64
+}
+
+// scoreRecommendations ranks every song against the seeds: a shared artist
+// scores 3, a shared album 2 and each shared genre 1 per seed track; an
+// artist seed scores 3 for the artist's own songs plus 1 per genre the
+// artist plays; a genre seed scores 2. min_/max_ tunables drop candidates,
+// target_ tunables cost points by distance. Seed tracks and songs in
+// exclude are never returned. Seed pool sizes are filled in on seeds.
+func scoreRecommendations(db *gorm.DB, seeds recommendationSeeds, tunables map[string]tunable, exclude map[int]bool) ([]scoredSong, []RecommendationSeed, error) {
+	var songs []models.Song
+	if err := db.Preload("Artist").Preload("Album").Preload("GenreTags").Find(&songs).Error; err != nil {
+		return nil, nil, err
+	}
+	var features map[int]models.AudioFeatures
+	if len(tunables) > 0 {
+		ids := make([]int, len(songs))
+		for i, s := range songs {
+			ids[i] = s.ID
+		}
+		var err error
+		if features, err = loadAudioFeatures(db, ids); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	byID := make(map[int]models.Song, len(songs))
+	artistGenres := make(map[int]map[string]bool)
+	for _, s := range songs {
+		byID[s.ID] = s
+		if artistGenres[s.ArtistID] == nil {
+			artistGenres[s.ArtistID] = make(map[string]bool)
+		}
+		for _, g := range s.GenreTags {
+			artistGenres[s.ArtistID][g.Slug] = true
+		}
+	}
+
+	seedInfo := make([]RecommendationSeed, 0, seeds.count())
+	seedTracks := make([]models.Song, 0, len(seeds.Tracks))
+	for _, id := range seeds.Tracks {
+		s, ok := byID[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w track %d", errUnknownSeed, id)
+		}
+		seedTracks = append(seedTracks, s)
+		seedInfo = append(seedInfo, RecommendationSeed{ID: strconv.Itoa(id), Type: "track", Href: fmt.Sprintf("/tracks/%d", id)})
+	}
+	for _, id := range seeds.Artists {
+		if _, ok := artistGenres[id]; !ok {
+			return nil, nil, fmt.Errorf("%w artist %d", errUnknownSeed, id)
+		}
+		seedInfo = append(seedInfo, RecommendationSeed{ID: strconv.Itoa(id), Type: "artist", Href: fmt.Sprintf("/artists/%d", id)})
+	}
+	for i, g := range seeds.Genres {
+		genre, err := findGenre(db, g)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("%w genre %q", errUnknownSeed, g)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		seeds.Genres[i] = genre.Slug
+		seedInfo = append(seedInfo, RecommendationSeed{ID: genre.Slug, Type: "genre", Href: "/genres/" + genre.Slug + "/tracks"})
+	}
+
+	isSeed := make(map[int]bool, len(seeds.Tracks))
+	for _, id := range seeds.Tracks {
+		isSeed[id] = true
+	}
+
+	out := make([]scoredSong, 0)
+	for _, s := range songs {
+		if isSeed[s.ID] || exclude[s.ID] {
+			continue
+		}
+		genres := make(map[string]bool, len(s.GenreTags))
+		for _, g := range s.GenreTags {
+			genres[g.Slug] = true
+		}
+
+		// per-seed contributions, in seedInfo order
+		contrib := make([]float64, 0, len(seedInfo))
+		for _, t := range seedTracks {
+			score := 0.0
+			if t.ArtistID == s.ArtistID {
+				score += 3
+			}
+			if t.AlbumID == s.AlbumID {
+				score += 2
+			}
+			for _, g := range t.GenreTags {
+				if genres[g.Slug] {
+					score++
+				}
+			}
+			contrib = append(contrib, score)
+		}
+		for _, id := range seeds.Artists {
+			score := 0.0
+			if s.ArtistID == id {
+				score += 3
+			}
+			for g := range genres {
+				if artistGenres[id][g] {
+					score++
 				}
-				placeholders := strings.Repeat("?,", len(args))
-				placeholders = strings.TrimRight(placeholders, ",")
-				where := "s.id IN (" + placeholders + ")"
-				recs, err = loadTracks(where, args...)
 			}
+			contrib = append(contrib, score)
+		}
+		for _, g := range seeds.Genres {
+			score := 0.0
+			if genres[g] {
+				score += 2
+			}
+			contrib = append(contrib, score)
+		}
+
+		total := 0.0
+		for i, score := range contrib {
+			if score > 0 {
+				seedInfo[i].InitialPoolSize++
+			}
+			total += score
+		}
+		if total == 0 {
+			continue
+		}
+
+		passes := true
+		for attr, t := range tunables {
+			a := trackAttributes[attr]
+			v := a.value(features[s.ID])
+			if (t.min != nil && v < *t.min) || (t.max != nil && v > *t.max) {
+				passes = false
+				break
+			}
+			if t.target != nil {
+				total -= math.Abs(v-*t.target) / a.scale
+			}
+		}
+		if !passes {
+			continue
+		}
+		for i, score := range contrib {
+			if score > 0 {
+				seedInfo[i].AfterFilteringSize++
+				seedInfo[i].AfterRelinkingSize++
+			}
+		}
+		out = append(out, scoredSong{Song: s, Score: total})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
 		}
+		return out[i].Song.ID < out[j].Song.ID
+	})
+	return out, seedInfo, nil
+}
 
-		// 3) Random fallback
-		if err == nil && len(recs) == 0 {
-			recs, err = loadTracks("1=1") // no WHERE, pure random
+// parseIDList splits a comma-separated list of integer IDs.
+func parseIDList(raw string) ([]int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	ids := make([]int, 0, len(parts))
+	for _, p := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", p)
 		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseNameList splits a comma-separated list of names, dropping blanks.
+func parseNameList(raw string) []string {
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// GetSeedRecommendations handles
+// GET /recommendations?seed_tracks=1,2&seed_artists=3&seed_genres=pop&limit=20&target_duration_ms=200000
+func GetSeedRecommendations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 || limit > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+
+		var seeds recommendationSeeds
+		if seeds.Tracks, err = parseIDList(c.Query("seed_tracks")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seed_tracks: " + err.Error()})
+			return
+		}
+		if seeds.Artists, err = parseIDList(c.Query("seed_artists")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seed_artists: " + err.Error()})
+			return
+		}
+		seeds.Genres = parseNameList(c.Query("seed_genres"))
+		if seeds.count() == 0 || seeds.count() > maxRecommendationSeeds {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("between 1 and %d seeds are required", maxRecommendationSeeds)})
+			return
+		}
+
+		tunables, err := parseTunables(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load recommendations"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, recs)
+		scored, seedInfo, err := scoreRecommendations(db, seeds, tunables, nil)
+		if errors.Is(err, errUnknownSeed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load recommendations"})
+			return
+		}
+		if len(scored) > limit {
+			scored = scored[:limit]
+		}
+
+		tracks := make([]models.TrackResponse, len(scored))
+		for i, s := range scored {
+			tracks[i] = newTrackResponse(s.Song)
+		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, RecommendationsResponse{Seeds: seedInfo, Tracks: tracks})
 	}
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"spotify-mock-api/internal/models"
+	"strings"
+	"testing"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+// scoreShape renders scored songs as "id:score", best first.
+func scoreShape(songs []scoredSong) string {
+	parts := make([]string, len(songs))
+	for i, s := range songs {
+		parts[i] = fmt.Sprintf("%d:%g", s.Song.ID, s.Score)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestScoreRecommendations(t *testing.T) {
+	db := newTestDB(t, &models.Artist{}, &models.Album{}, &models.Genre{}, &models.Song{},
+		&models.SongGenre{}, &models.AudioFeatures{})
+	pop := models.Genre{ID: 1, Name: "Pop", Slug: "pop"}
+	rock := models.Genre{ID: 2, Name: "Rock", Slug: "rock"}
+	rows := []interface{}{
+		&models.Artist{ArtistId: 1, Name: "One"},
+		&models.Artist{ArtistId: 2, Name: "Two"},
+		&models.Album{AlbumId: 1, Title: "First"},
+		&models.Album{AlbumId: 2, Title: "Second"},
+		&pop, &rock,
+		&models.Song{ID: 10, Title: "Seed", ArtistID: 1, AlbumID: 1, GenreTags: []models.Genre{pop}},
+		&models.Song{ID: 11, Title: "Same album", ArtistID: 1, AlbumID: 1, GenreTags: []models.Genre{pop}},
+		&models.Song{ID: 12, Title: "Same artist", ArtistID: 1, AlbumID: 2, GenreTags: []models.Genre{rock}},
+		&models.Song{ID: 13, Title: "Same genre", ArtistID: 2, AlbumID: 2, GenreTags: []models.Genre{pop}},
+		&models.Song{ID: 14, Title: "Nothing shared", ArtistID: 2, AlbumID: 2, GenreTags: []models.Genre{rock}},
+		&models.AudioFeatures{SongID: 10, Tempo: 100},
+		&models.AudioFeatures{SongID: 11, Tempo: 100},
+		&models.AudioFeatures{SongID: 12, Tempo: 150},
+		&models.AudioFeatures{SongID: 13, Tempo: 120},
+		&models.AudioFeatures{SongID: 14, Tempo: 120},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		seeds    recommendationSeeds
+		tunables map[string]tunable
+		exclude  map[int]bool
+		want     string
+	}{
+		{
+			name:  "track seed: artist, album and genres",
+			seeds: recommendationSeeds{Tracks: []int{10}},
+			want:  "11:6 12:3 13:1",
+		},
+		{
+			name:    "excluded songs are left out",
+			seeds:   recommendationSeeds{Tracks: []int{10}},
+			exclude: map[int]bool{11: true},
+			want:    "12:3 13:1",
+		},
+		{
+			name:  "artist seed: own songs and genres the artist plays",
+			seeds: recommendationSeeds{Artists: []int{2}},
+			want:  "13:4 14:4 10:1 11:1 12:1",
+		},
+		{
+			name:  "genre seed by name",
+			seeds: recommendationSeeds{Genres: []string{"Rock"}},
+			want:  "12:2 14:2",
+		},
+		{
+			name:  "seeds add up",
+			seeds: recommendationSeeds{Tracks: []int{10}, Genres: []string{"rock"}},
+			want:  "11:6 12:5 14:2 13:1",
+		},
+		{
+			name:     "min and max drop songs",
+			seeds:    recommendationSeeds{Tracks: []int{10}},
+			tunables: map[string]tunable{"tempo": {min: floatPtr(110), max: floatPtr(140)}},
+			want:     "13:1",
+		},
+		{
+			name:     "target costs points by distance",
+			seeds:    recommendationSeeds{Tracks: []int{10}},
+			tunables: map[string]tunable{"tempo": {target: floatPtr(110)}},
+			want:     "11:5.5 12:1 13:0.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := scoreRecommendations(db, tt.seeds, tt.tunables, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shape := scoreShape(got); shape != tt.want {
+				t.Errorf("scoreRecommendations = %s, want %s", shape, tt.want)
+			}
+		})
+	}
+
+	t.Run("seed pool sizes", func(t *testing.T) {
+		_, seeds, err := scoreRecommendations(db, recommendationSeeds{Tracks: []int{10}, Genres: []string{"rock"}},
+			map[string]tunable{"tempo": {max: floatPtr(140)}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(seeds) != 2 {
+			t.Fatalf("got %d seeds, want 2", len(seeds))
+		}
+		for i, want := range [][2]int{{3, 2}, {2, 1}} {
+			if seeds[i].InitialPoolSize != want[0] || seeds[i].AfterFilteringSize != want[1] {
+				t.Errorf("seed %s pool = %d/%d, want %d/%d", seeds[i].ID,
+					seeds[i].InitialPoolSize, seeds[i].AfterFilteringSize, want[0], want[1])
+			}
+		}
+	})
+
+	for _, seeds := range []recommendationSeeds{
+		{Tracks: []int{99}},
+		{Artists: []int{99}},
+		{Genres: []string{"polka"}},
+	} {
+		if _, _, err := scoreRecommendations(db, seeds, nil, nil); !errors.Is(err, errUnknownSeed) {
+			t.Errorf("scoreRecommendations(%+v) error = %v, want errUnknownSeed", seeds, err)
+		}
+	}
+}
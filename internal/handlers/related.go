@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	relatedArtistsLimit = 20
+	fansAlsoLikeLimit   = 10
+	// playlistAffinity is what each playlist two artists share adds to
+	// their score, next to at most 1 from genre overlap.
+	playlistAffinity = 0.5
+)
+
+// relatedArtistCache holds every artist's related-artist scores, built from
+// the catalog as it was when fingerprint was taken.
+type relatedArtistCache struct {
+	mu          sync.Mutex
+	fingerprint string
+	scores      map[int]map[int]float64 // artist → related artist → score
+}
+
+var relatedArtists relatedArtistCache
+
+// listenerPlaylistSongs selects the playlist_songs rows of playlists people
+// put together themselves. Generated and Wrapped playlists are built from
+// related artists and plays, and editorial ones are the catalog's own, so
+// counting them would only echo the recommendations back.
+const listenerPlaylistSongs = `
+	SELECT ps.playlist_id, ps.song_id
+	FROM playlist_songs ps
+	JOIN playlists p ON p.id = ps.playlist_id
+	WHERE COALESCE(p.generator, '') = ''
+	  AND p.user_id NOT IN (SELECT id FROM users WHERE name = @system)`
+
+// relatedArtistsFingerprint summarizes the rows related artists are built
+// from; it changes whenever a song, its credits, its genres or a
+// listener playlist's tracks change.
+func relatedArtistsFingerprint(db *gorm.DB) (string, error) {
+	var fp string
+	err := db.Raw(`
+		SELECT
+		  (SELECT COUNT(*) || ':' || COALESCE(SUM(id * 1000003 + artist_id), 0) FROM songs) || '|' ||
+		  (SELECT COUNT(*) || ':' || COALESCE(SUM(song_id * 1000003 + artist_id), 0) FROM song_credits) || '|' ||
+		  (SELECT COUNT(*) || ':' || COALESCE(SUM(song_id * 1000003 + genre_id), 0) FROM song_genres) || '|' ||
+		  (SELECT COUNT(*) || ':' || COALESCE(SUM(playlist_id * 1000003 + song_id), 0) FROM (`+listenerPlaylistSongs+`))
+	`, sql.Named("system", models.SystemUserName)).Row().Scan(&fp)
+	return fp, err
+}
+
+// loadRelatedArtists returns the related-artist scores, rebuilding them
+// first if the catalog changed since they were last built.
+func loadRelatedArtists(db *gorm.DB) (map[int]map[int]float64, error) {
+	fp, err := relatedArtistsFingerprint(db)
+	if err != nil {
+		return nil, err
+	}
+
+	relatedArtists.mu.Lock()
+	defer relatedArtists.mu.Unlock()
+	if relatedArtists.scores != nil && relatedArtists.fingerprint == fp {
+		return relatedArtists.scores, nil
+	}
+	scores, err := computeRelatedArtists(db)
+	if err != nil {
+		return nil, err
+	}
+	relatedArtists.fingerprint, relatedArtists.scores = fp, scores
+	return scores, nil
+}
+
+// computeRelatedArtists scores every pair of artists: the Jaccard
+// similarity of their genre sets plus playlistAffinity for each listener
+// playlist that has songs by both. A song counts for every artist performing on it.
+func computeRelatedArtists(db *gorm.DB) (map[int]map[int]float64, error) {
+	var songs []models.Song
+	if err := db.Preload("GenreTags").Select("id", "artist_id").Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	performers, err := songPerformers(db)
+	if err != nil {
+		return nil, err
+	}
+	genres := make(map[int]map[int]bool)
+	for _, s := range songs {
+		for _, a := range performers[s.ID] {
+			if genres[a] == nil {
+				genres[a] = make(map[int]bool)
+			}
+			for _, g := range s.GenreTags {
+				genres[a][g.ID] = true
+			}
+		}
+	}
+
+	var rows []struct {
+		PlaylistID int
+		SongID     int
+	}
+	if err := db.
+		Raw(listenerPlaylistSongs, sql.Named("system", models.SystemUserName)).
+		Scan(&rows).
+		Error; err != nil {
+		return nil, err
+	}
+	playlists := make(map[int]map[int]bool)
+	for _, r := range rows {
+		if playlists[r.PlaylistID] == nil {
+			playlists[r.PlaylistID] = make(map[int]bool)
+		}
+		for _, a := range performers[r.SongID] {
+			playlists[r.PlaylistID][a] = true
+		}
+	}
+
+	scores := make(map[int]map[int]float64, len(genres))
+	add := func(a, b int, v float64) {
+		if scores[a] == nil {
+			scores[a] = make(map[int]float64)
+		}
+		scores[a][b] += v
+	}
+	for a, ga := range genres {
+		for b, gb := range genres {
+			if a == b {
+				continue
+			}
+			shared, union := 0, len(gb)
+			for g := range ga {
+				if gb[g] {
+					shared++
+				} else {
+					union++
+				}
+			}
+			if shared > 0 {
+				add(a, b, float64(shared)/float64(union))
+			}
+		}
+	}
+	for _, artists := range playlists {
+		for a := range artists {
+			for b := range artists {
+				if a != b {
+					add(a, b, playlistAffinity)
+				}
+			}
+		}
+	}
+	return scores, nil
+}
+
+// relatedArtistResponses ranks the artists related to artistID, best
+// first, and loads up to limit of them.
+func relatedArtistResponses(db *gorm.DB, artistID, limit int) ([]ArtistResponse, error) {
+	scores, err := loadRelatedArtists(db)
+	if err != nil {
+		return nil, err
+	}
+	ids := firstN(rankIDs(scores[artistID]), limit)
+
+	var artists []models.Artist
+	if len(ids) > 0 {
+		if err := db.Where("artist_id IN ?", ids).Find(&artists).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[int]models.Artist, len(artists))
+	for _, a := range artists {
+		byID[a.ArtistId] = a
+	}
+	out := make([]ArtistResponse, 0, len(ids))
+	for _, id := range ids {
+		if a, ok := byID[id]; ok {
+			out = append(out, ArtistResponse{ID: a.ArtistId, Name: a.Name, Image: a.Image})
+		}
+	}
+	return out, nil
+}
+
+// GET /artists/:id/related-artists
+func GetRelatedArtists(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		artistID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid artist ID"})
+			return
+		}
+		var artist models.Artist
+		if err := db.First(&artist, "artist_id = ?", artistID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artist not found"})
+			return
+		}
+
+		related, err := relatedArtistResponses(db, artistID, relatedArtistsLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load related artists"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"artists": related})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"spotify-mock-api/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ResumePoint is where the user left off in a podcast episode.
+type ResumePoint struct {
+	PositionMs  int  `json:"position_ms"`
+	FullyPlayed bool `json:"fully_played"`
+}
+
+type resumePointRequest struct {
+	PositionMs  *int `json:"position_ms" binding:"required"`
+	FullyPlayed bool `json:"fully_played"`
+}
+
+// loadResumePoints returns the user's resume points for the given episodes;
+// episodes never played are missing from the map, which reads as position 0.
+func loadResumePoints(db *gorm.DB, userID int, ids []int) (map[int]ResumePoint, error) {
+	points := make(map[int]ResumePoint, len(ids))
+	if len(ids) == 0 {
+		return points, nil
+	}
+	var rows []models.EpisodeProgress
+	if err := db.
+		Where("user_id = ? AND episode_id IN ?", userID, ids).
+		Find(&rows).
+		Error; err != nil {
+		return points, err
+	}
+	for _, p := range rows {
+		points[p.EpisodeID] = ResumePoint{PositionMs: p.PositionMs, FullyPlayed: p.FullyPlayed}
+	}
+	return points, nil
+}
+
+// saveResumePoint stores where the user is in an episode, given where they
+// were before. Reaching the end, or the point it is first marked fully
+// played, rewinds it so playing it again starts over; after that a re-listen
+// keeps its position and the episode stays fully played.
+func saveResumePoint(db *gorm.DB, userID int, ep models.PodcastEpisode, previous, point ResumePoint) (ResumePoint, error) {
+	ended := ep.Duration > 0 && point.PositionMs >= ep.Duration*1000
+	if ended {
+		point.FullyPlayed = true
+	}
+	if ended || (point.FullyPlayed && !previous.FullyPlayed) {
+		point.PositionMs = 0
+	}
+	row := models.EpisodeProgress{
+		UserID:      userID,
+		EpisodeID:   ep.ID,
+		PositionMs:  point.PositionMs,
+		FullyPlayed: point.FullyPlayed,
+	}
+	err := db.
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&row).
+		Error
+	return point, err
+}
+
+// advanceResumePoint moves the resume point after a reported play: to
+// position_ms when the client sent it, else ms_played past where the user
+// left off. An episode stays fully played however little of it is heard
+// again; only PUT /me/episodes/:id/resume-point marks it unplayed.
+func advanceResumePoint(db *gorm.DB, userID int, ep models.PodcastEpisode, body reportPlayRequest) error {
+	points, err := loadResumePoints(db, userID, []int{ep.ID})
+	if err != nil {
+		return err
+	}
+	previous := points[ep.ID]
+	point := ResumePoint{PositionMs: previous.PositionMs + body.MsPlayed}
+	if body.PositionMs != nil {
+		point.PositionMs = *body.PositionMs
+	}
+	point.FullyPlayed = previous.FullyPlayed || body.ReasonEnd == "trackdone"
+	_, err = saveResumePoint(db, userID, ep, previous, point)
+	return err
+}
+
+// PUT /me/episodes/:id/resume-point
+// Body: { "position_ms": 754000, "fully_played": false }
+func SaveResumePoint(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		episodeID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid episode ID"})
+			return
+		}
+		var body resumePointRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "position_ms is required"})
+			return
+		}
+		if *body.PositionMs < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "position_ms cannot be negative"})
+			return
+		}
+		var ep models.PodcastEpisode
+		if err := db.First(&ep, "id = ?", episodeID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "episode not found"})
+			return
+		}
+
+		points, err := loadResumePoints(db, userID, []int{ep.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load resume point"})
+			return
+		}
+		point, err := saveResumePoint(db, userID, ep, points[ep.ID], ResumePoint{
+			PositionMs:  *body.PositionMs,
+			FullyPlayed: body.FullyPlayed,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save resume point"})
+			return
+		}
+		c.JSON(http.StatusOK, point)
+	}
+}
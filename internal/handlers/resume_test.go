@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"spotify-mock-api/internal/models"
+	"testing"
+)
+
+func TestSaveResumePoint(t *testing.T) {
+	ep := models.PodcastEpisode{ID: 1, Duration: 600}
+	tests := []struct {
+		name     string
+		previous ResumePoint
+		point    ResumePoint
+		want     ResumePoint
+	}{
+		{"part way", ResumePoint{}, ResumePoint{PositionMs: 60000}, ResumePoint{PositionMs: 60000}},
+		{"reaching the end rewinds", ResumePoint{PositionMs: 590000}, ResumePoint{PositionMs: 600000}, ResumePoint{FullyPlayed: true}},
+		{"past the end rewinds", ResumePoint{}, ResumePoint{PositionMs: 700000}, ResumePoint{FullyPlayed: true}},
+		{"marking it played rewinds", ResumePoint{PositionMs: 60000}, ResumePoint{PositionMs: 60000, FullyPlayed: true}, ResumePoint{FullyPlayed: true}},
+		{"re-listening keeps the position", ResumePoint{FullyPlayed: true}, ResumePoint{PositionMs: 60000, FullyPlayed: true}, ResumePoint{PositionMs: 60000, FullyPlayed: true}},
+		{"re-listening to the end rewinds", ResumePoint{PositionMs: 60000, FullyPlayed: true}, ResumePoint{PositionMs: 600000, FullyPlayed: true}, ResumePoint{FullyPlayed: true}},
+		{"marking it unplayed", ResumePoint{FullyPlayed: true}, ResumePoint{PositionMs: 30000}, ResumePoint{PositionMs: 30000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.EpisodeProgress{})
+			got, err := saveResumePoint(db, 1, ep, tt.previous, tt.point)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("saveResumePoint = %+v, want %+v", got, tt.want)
+			}
+			points, err := loadResumePoints(db, 1, []int{ep.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if points[ep.ID] != tt.want {
+				t.Errorf("stored %+v, want %+v", points[ep.ID], tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvanceResumePointAfterFinishing(t *testing.T) {
+	db := newTestDB(t, &models.EpisodeProgress{})
+	ep := models.PodcastEpisode{ID: 1, Duration: 600}
+	play := func(body reportPlayRequest) ResumePoint {
+		t.Helper()
+		if err := advanceResumePoint(db, 1, ep, body); err != nil {
+			t.Fatal(err)
+		}
+		points, err := loadResumePoints(db, 1, []int{ep.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return points[ep.ID]
+	}
+
+	if got, want := play(reportPlayRequest{MsPlayed: 300000}), (ResumePoint{PositionMs: 300000}); got != want {
+		t.Errorf("after half = %+v, want %+v", got, want)
+	}
+	if got, want := play(reportPlayRequest{MsPlayed: 290000, ReasonEnd: "trackdone"}), (ResumePoint{FullyPlayed: true}); got != want {
+		t.Errorf("after finishing = %+v, want %+v", got, want)
+	}
+	if got, want := play(reportPlayRequest{MsPlayed: 60000}), (ResumePoint{PositionMs: 60000, FullyPlayed: true}); got != want {
+		t.Errorf("after playing 60s again = %+v, want %+v", got, want)
+	}
+	at := 120000
+	if got, want := play(reportPlayRequest{MsPlayed: 5000, PositionMs: &at}), (ResumePoint{PositionMs: 120000, FullyPlayed: true}); got != want {
+		t.Errorf("after seeking = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// itunesNS is the namespace of the itunes: tags in podcast feeds.
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// maxFeedSize caps how much of a remote feed an import reads.
+const maxFeedSize = 10 << 20
+
+// feedClient fetches remote feeds for import.
+var feedClient = &http.Client{Timeout: 30 * time.Second}
+
+// importFeedsDir is the only place POST /admin/podcasts/import reads local
+// feeds from; the import-feed command takes any path.
+var importFeedsDir = filepath.Join("data", "feeds")
+
+// rssFeed is the subset of an RSS 2.0 podcast feed, with iTunes tags, that
+// an import reads.
+type rssFeed struct {
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string     `xml:"title"`
+	Description string     `xml:"description"`
+	Language    string     `xml:"language"`
+	Author      string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	Explicit    string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	Images      []rssImage `xml:"image"`
+	AtomLinks   []rssLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Items       []rssItem  `xml:"item"`
+}
+
+// rssImage matches both <image><url>…</url></image> and
+// <itunes:image href="…"/>.
+type rssImage struct {
+	XMLName xml.Name
+	URL     string `xml:"url"`
+	Href    string `xml:"href,attr"`
+}
+
+type rssLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Description string     `xml:"description"`
+	Summary     string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	PubDate     string     `xml:"pubDate"`
+	GUID        string     `xml:"guid"`
+	Enclosure   rssEncl    `xml:"enclosure"`
+	Duration    string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Explicit    string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	Episode     int        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Images      []rssImage `xml:"image"`
+}
+
+type rssEncl struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// pickImage prefers the iTunes artwork over the plain RSS image.
+func pickImage(images []rssImage) string {
+	for _, img := range images {
+		if img.XMLName.Space == itunesNS && img.Href != "" {
+			return img.Href
+		}
+	}
+	for _, img := range images {
+		if img.URL != "" {
+			return strings.TrimSpace(img.URL)
+		}
+	}
+	return ""
+}
+
+// feedExplicit reads itunes:explicit, which feeds spell many ways.
+func feedExplicit(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "yes", "true", "explicit":
+		return true
+	}
+	return false
+}
+
+// feedDuration reads itunes:duration as seconds: "1:02:03", "62:03" or
+// "3723". Anything else, negative numbers included, reads as 0.
+func feedDuration(v string) int {
+	v = strings.TrimSpace(v)
+	parts := strings.Split(v, ":")
+	if v == "" || len(parts) > 3 {
+		return 0
+	}
+	secs := 0
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+			return 0
+		}
+		secs = secs*60 + int(n)
+	}
+	return secs
+}
+
+var pubDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// feedPubDate reads an RSS pubDate; ok is false if no layout fits.
+func feedPubDate(v string) (t time.Time, ok bool) {
+	v = strings.TrimSpace(v)
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// FeedImportResult sums up one import of a podcast feed.
+type FeedImportResult struct {
+	PodcastID       int    `json:"podcast_id"`
+	Title           string `json:"title"`
+	FeedURL         string `json:"feed_url"`
+	Created         bool   `json:"created"` // false when the show was imported before
+	EpisodesCreated int    `json:"episodes_created"`
+	EpisodesUpdated int    `json:"episodes_updated"`
+}
+
+// isFeedURL reports whether source is an http(s) URL rather than a file.
+func isFeedURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// readFeed loads a feed from an http(s) URL, up to maxFeedSize, or a local
+// file.
+func readFeed(source string) ([]byte, error) {
+	if isFeedURL(source) {
+		resp, err := feedClient.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch %s: %s", source, resp.Status)
+		}
+		raw, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) > maxFeedSize {
+			return nil, fmt.Errorf("fetch %s: feed is larger than %d bytes", source, maxFeedSize)
+		}
+		return raw, nil
+	}
+	return os.ReadFile(source)
+}
+
+// inFeedsDir reports whether the file at source, symlinks resolved, sits
+// under importFeedsDir.
+func inFeedsDir(source string) bool {
+	dir, err := filepath.EvalSymlinks(importFeedsDir)
+	if err != nil {
+		return false
+	}
+	file, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return false
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return false
+	}
+	if file, err = filepath.Abs(file); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ImportPodcastFeed reads an RSS 2.0 / iTunes podcast feed from a URL or
+// local file and upserts it: the show is matched on its feed URL (the
+// feed's atom:link rel="self", else source) and episodes on their guid,
+// so importing the same feed again updates rather than duplicates.
+// Episodes that left the feed are kept.
+func ImportPodcastFeed(db *gorm.DB, source string) (FeedImportResult, error) {
+	var result FeedImportResult
+	raw, err := readFeed(source)
+	if err != nil {
+		return result, err
+	}
+	var feed rssFeed
+	if err := xml.Unmarshal(raw, &feed); err != nil {
+		return result, fmt.Errorf("parse feed: %w", err)
+	}
+	ch := feed.Channel
+	if strings.TrimSpace(ch.Title) == "" {
+		return result, errors.New("feed has no channel title")
+	}
+
+	feedURL := source
+	for _, l := range ch.AtomLinks {
+		if l.Rel == "self" && l.Href != "" {
+			feedURL = l.Href
+		}
+	}
+	result.FeedURL = feedURL
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var show models.Podcast
+		err := tx.Where("feed_url = ?", feedURL).First(&show).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		result.Created = errors.Is(err, gorm.ErrRecordNotFound)
+
+		show.FeedURL = feedURL
+		show.Title = strings.TrimSpace(ch.Title)
+		show.Description = strings.TrimSpace(ch.Description)
+		show.Publisher = strings.TrimSpace(ch.Author)
+		show.Language = strings.TrimSpace(ch.Language)
+		show.Explicit = feedExplicit(ch.Explicit)
+		if cover := pickImage(ch.Images); cover != "" {
+			show.Cover = cover
+		} else if show.Cover == "" {
+			show.Cover = "/media/podcast-art.jpg"
+		}
+		if show.Publisher != "" {
+			hosts, _ := json.Marshal([]string{show.Publisher})
+			show.Hosts = hosts
+		}
+		if err := tx.Save(&show).Error; err != nil {
+			return err
+		}
+		result.PodcastID, result.Title = show.ID, show.Title
+
+		var existing []models.PodcastEpisode
+		if err := tx.Where("podcast_id = ?", show.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byGUID := make(map[string]models.PodcastEpisode, len(existing))
+		lastNumber := 0
+		for _, ep := range existing {
+			byGUID[ep.GUID] = ep
+			if ep.Number > lastNumber {
+				lastNumber = ep.Number
+			}
+		}
+
+		// feeds list newest first; number episodes oldest first
+		for i := len(ch.Items) - 1; i >= 0; i-- {
+			it := ch.Items[i]
+			guid := strings.TrimSpace(it.GUID)
+			if guid == "" {
+				guid = it.Enclosure.URL
+			}
+			if guid == "" {
+				guid = strings.TrimSpace(it.Title)
+			}
+
+			ep, found := byGUID[guid]
+			ep.PodcastID = show.ID
+			ep.GUID = guid
+			ep.Title = strings.TrimSpace(it.Title)
+			ep.Description = strings.TrimSpace(it.Description)
+			if ep.Description == "" {
+				ep.Description = strings.TrimSpace(it.Summary)
+			}
+			ep.AudioURL = it.Enclosure.URL
+			ep.Duration = feedDuration(it.Duration)
+			ep.Explicit = feedExplicit(it.Explicit)
+			ep.Image = pickImage(it.Images)
+			// UTC, as release dates are compared as stored
+			if t, ok := feedPubDate(it.PubDate); ok {
+				ep.ReleaseDate = t.UTC()
+			} else if !found {
+				ep.ReleaseDate = time.Now().UTC()
+			}
+			switch {
+			case it.Episode > 0:
+				ep.Number = it.Episode
+			case !found:
+				ep.Number = lastNumber + 1
+			}
+			if ep.Number > lastNumber {
+				lastNumber = ep.Number
+			}
+
+			if err := tx.Save(&ep).Error; err != nil {
+				return err
+			}
+			byGUID[guid] = ep
+			if found {
+				result.EpisodesUpdated++
+			} else {
+				result.EpisodesCreated++
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+type importFeedRequest struct {
+	Source string `json:"source" binding:"required"` // feed URL or path to a file under data/feeds
+}
+
+// POST /admin/podcasts/import
+// Body: { "source": "https://example.com/feed.xml" } or a file under
+// data/feeds, e.g. "data/feeds/sample-show.xml". Only routed when
+// ENABLE_ADMIN=1. Answers 201 when the show is new, 200 when an earlier
+// import was updated.
+func ImportPodcast(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body importFeedRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source is required"})
+			return
+		}
+		if !isFeedURL(body.Source) && !inFeedsDir(body.Source) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source must be an http(s) URL or a file under data/feeds"})
+			return
+		}
+
+		result, err := ImportPodcastFeed(db, body.Source)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "cannot import feed: " + err.Error()})
+			return
+		}
+		status := http.StatusOK
+		if result.Created {
+			status = http.StatusCreated
+		}
+		c.JSON(status, result)
+	}
+}
+
+// rssOutFeed is a podcast feed as rendered by GET /shows/:id/feed.xml.
+// encoding/xml cannot pick namespace prefixes, so the itunes: and atom:
+// names are spelled out and their namespaces declared on <rss>.
+type rssOutFeed struct {
+	XMLName  xml.Name      `xml:"rss"`
+	Version  string        `xml:"version,attr"`
+	ItunesNS string        `xml:"xmlns:itunes,attr"`
+	AtomNS   string        `xml:"xmlns:atom,attr"`
+	Channel  rssOutChannel `xml:"channel"`
+}
+
+type rssOutChannel struct {
+	Title       string          `xml:"title"`
+	Link        string          `xml:"link"`
+	AtomLink    rssOutAtomLink  `xml:"atom:link"`
+	Description string          `xml:"description"`
+	Language    string          `xml:"language,omitempty"`
+	Author      string          `xml:"itunes:author,omitempty"`
+	Explicit    string          `xml:"itunes:explicit"`
+	ItunesImage rssOutItunesImg `xml:"itunes:image"`
+	Image       rssOutImage     `xml:"image"`
+	Items       []rssOutItem    `xml:"item"`
+}
+
+type rssOutAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssOutItunesImg struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssOutImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssOutGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssOutItem struct {
+	Title       string           `xml:"title"`
+	Description string           `xml:"description"`
+	PubDate     string           `xml:"pubDate"`
+	GUID        rssOutGUID       `xml:"guid"`
+	Enclosure   rssEncl          `xml:"enclosure"`
+	Duration    int              `xml:"itunes:duration"` // in seconds
+	Episode     int              `xml:"itunes:episode,omitempty"`
+	Explicit    string           `xml:"itunes:explicit"`
+	Image       *rssOutItunesImg `xml:"itunes:image,omitempty"`
+}
+
+// absoluteURL turns a path served by this mock, like /media/song.mp3, into
+// a URL on the host the request came in on; other URLs pass through.
+func absoluteURL(c *gin.Context, p string) string {
+	if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+		return p
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, c.Request.Host, strings.TrimPrefix(p, "/"))
+}
+
+// enclosureFor describes an episode's audio for <enclosure>; the length is
+// only known for files in the media directory.
+func enclosureFor(c *gin.Context, ep models.PodcastEpisode) rssEncl {
+	audio := episodeAudioURL(ep)
+	enc := rssEncl{URL: absoluteURL(c, audio), Type: "audio/mpeg"}
+	if t := mime.TypeByExtension(path.Ext(audio)); t != "" {
+		enc.Type = t
+	}
+	if strings.HasPrefix(audio, "/media/") {
+		if fi, err := os.Stat(filepath.FromSlash(strings.TrimPrefix(audio, "/"))); err == nil {
+			enc.Length = fi.Size()
+		}
+	}
+	return enc
+}
+
+// GET /shows/:id/feed.xml
+// Renders the show as an RSS 2.0 podcast feed with iTunes tags, newest
+// episode first; media URLs point back at this server.
+func GetShowFeed(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		showID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid show ID"})
+			return
+		}
+		var podcast models.Podcast
+		if err := db.First(&podcast, showID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "show not found"})
+			return
+		}
+		var episodes []models.PodcastEpisode
+		if err := db.
+			Where("podcast_id = ?", podcast.ID).
+			Order("release_date DESC, number DESC, id DESC").
+			Find(&episodes).
+			Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load episodes"})
+			return
+		}
+
+		link := absoluteURL(c, fmt.Sprintf("/podcasts/%d", podcast.ID))
+		cover := absoluteURL(c, podcast.Cover)
+		author := podcast.Publisher
+		if author == "" {
+			author = strings.Join(podcastHosts(podcast), ", ")
+		}
+		description := podcast.Description
+		if description == "" {
+			description = podcast.Title // players expect one
+		}
+		ch := rssOutChannel{
+			Title:       podcast.Title,
+			Link:        link,
+			AtomLink:    rssOutAtomLink{Href: absoluteURL(c, c.Request.URL.Path), Rel: "self", Type: "application/rss+xml"},
+			Description: description,
+			Language:    podcast.Language,
+			Author:      author,
+			Explicit:    strconv.FormatBool(podcast.Explicit),
+			ItunesImage: rssOutItunesImg{Href: cover},
+			Image:       rssOutImage{URL: cover, Title: podcast.Title, Link: link},
+			Items:       make([]rssOutItem, len(episodes)),
+		}
+		for i, ep := range episodes {
+			guid := ep.GUID
+			if guid == "" {
+				guid = fmt.Sprintf("spotify-mock-episode-%d", ep.ID)
+			}
+			item := rssOutItem{
+				Title:       ep.Title,
+				Description: ep.Description,
+				PubDate:     ep.ReleaseDate.UTC().Format(time.RFC1123Z),
+				GUID:        rssOutGUID{Value: guid},
+				Enclosure:   enclosureFor(c, ep),
+				Duration:    ep.Duration,
+				Episode:     ep.Number,
+				Explicit:    strconv.FormatBool(ep.Explicit),
+			}
+			if ep.Image != "" {
+				item.Image = &rssOutItunesImg{Href: absoluteURL(c, ep.Image)}
+			}
+			ch.Items[i] = item
+		}
+
+		out, err := xml.MarshalIndent(rssOutFeed{
+			Version:  "2.0",
+			ItunesNS: itunesNS,
+			AtomNS:   "http://www.w3.org/2005/Atom",
+			Channel:  ch,
+		}, "", "  ")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot render feed"})
+			return
+		}
+		c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), out...))
+	}
+}
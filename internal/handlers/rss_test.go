@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"spotify-mock-api/internal/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const sampleFeed = "../../data/feeds/sample-show.xml"
+
+// newFeedTestDB opens an empty in-memory database with the podcast tables.
+func newFeedTestDB(t *testing.T) *gorm.DB {
+	return newTestDB(t, &models.Podcast{}, &models.PodcastEpisode{})
+}
+
+func TestImportPodcastFeedTwice(t *testing.T) {
+	db := newFeedTestDB(t)
+
+	first, err := ImportPodcastFeed(db, sampleFeed)
+	if err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	if !first.Created || first.EpisodesCreated != 3 || first.EpisodesUpdated != 0 {
+		t.Errorf("first import = %+v, want a new show with 3 new episodes", first)
+	}
+
+	second, err := ImportPodcastFeed(db, sampleFeed)
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	if second.Created || second.EpisodesCreated != 0 || second.EpisodesUpdated != 3 {
+		t.Errorf("second import = %+v, want the same show with 3 updated episodes", second)
+	}
+	if second.PodcastID != first.PodcastID {
+		t.Errorf("second import made podcast %d, want %d", second.PodcastID, first.PodcastID)
+	}
+
+	var shows int64
+	db.Model(&models.Podcast{}).Count(&shows)
+	if shows != 1 {
+		t.Errorf("%d podcasts after two imports, want 1", shows)
+	}
+
+	var episodes []models.PodcastEpisode
+	if err := db.Order("number").Find(&episodes).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		guid     string
+		duration int
+		released time.Time
+	}{
+		{"mock-hour-001", 3665, time.Date(2025, 5, 5, 7, 0, 0, 0, time.UTC)},
+		{"mock-hour-002", 2215, time.Date(2025, 5, 12, 7, 0, 0, 0, time.UTC)},
+		{"mock-hour-003", 2952, time.Date(2025, 5, 19, 7, 0, 0, 0, time.UTC)},
+	}
+	if len(episodes) != len(want) {
+		t.Fatalf("%d episodes, want %d", len(episodes), len(want))
+	}
+	for i, ep := range episodes {
+		w := want[i]
+		if ep.Number != i+1 || ep.GUID != w.guid || ep.Duration != w.duration {
+			t.Errorf("episode %d = #%d %s %ds, want #%d %s %ds", i, ep.Number, ep.GUID, ep.Duration, i+1, w.guid, w.duration)
+		}
+		if !ep.ReleaseDate.Equal(w.released) || ep.ReleaseDate.Location() != time.UTC {
+			t.Errorf("episode %d released %v, want %v", i, ep.ReleaseDate, w.released)
+		}
+	}
+}
+
+func TestImportPodcastFeedGUIDFallbackAndNumbering(t *testing.T) {
+	db := newFeedTestDB(t)
+	feed := filepath.Join(t.TempDir(), "feed.xml")
+	write := func(items string) {
+		t.Helper()
+		xml := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Fallbacks</title>
+    ` + items + `
+  </channel>
+</rss>`
+		if err := os.WriteFile(feed, []byte(xml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// newest first: the enclosure and the title stand in for missing guids,
+	// and episodes without itunes:episode are numbered oldest first
+	write(`<item><title>Second</title><pubDate>Tue, 06 May 2025 09:00:00 +0200</pubDate></item>
+    <item><title>First</title><enclosure url="https://example.com/first.mp3" type="audio/mpeg"/></item>`)
+	res, err := ImportPodcastFeed(db, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EpisodesCreated != 2 {
+		t.Fatalf("created %d episodes, want 2", res.EpisodesCreated)
+	}
+
+	write(`<item><title>Third</title><guid>third</guid></item>
+    <item><title>Second</title></item>
+    <item><title>First</title><enclosure url="https://example.com/first.mp3" type="audio/mpeg"/></item>`)
+	res, err = ImportPodcastFeed(db, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EpisodesCreated != 1 || res.EpisodesUpdated != 2 {
+		t.Errorf("re-import = %+v, want 1 new and 2 updated episodes", res)
+	}
+
+	var episodes []models.PodcastEpisode
+	if err := db.Order("number").Find(&episodes).Error; err != nil {
+		t.Fatal(err)
+	}
+	wantGUIDs := []string{"https://example.com/first.mp3", "Second", "third"}
+	if len(episodes) != len(wantGUIDs) {
+		t.Fatalf("%d episodes, want %d", len(episodes), len(wantGUIDs))
+	}
+	for i, ep := range episodes {
+		if ep.Number != i+1 || ep.GUID != wantGUIDs[i] {
+			t.Errorf("episode %d = #%d %q, want #%d %q", i, ep.Number, ep.GUID, i+1, wantGUIDs[i])
+		}
+	}
+	// a pubDate that later goes missing keeps the date first imported
+	if want := time.Date(2025, 5, 6, 7, 0, 0, 0, time.UTC); !episodes[1].ReleaseDate.Equal(want) {
+		t.Errorf("Second released %v, want %v", episodes[1].ReleaseDate, want)
+	}
+}
+
+func TestFeedDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"1:02:03", 3723},
+		{"62:03", 3723},
+		{"3723", 3723},
+		{"0:00:45", 45},
+		{"01:00:00", 3600},
+		{" 45 ", 45},
+		{"12.5", 12},
+		{"1:02.9", 62},
+		{"", 0},
+		{"soon", 0},
+		{"1:xx", 0},
+		{"1::02", 0},
+		{"1:02:03:04", 0},
+		{"-5", 0},
+		{"1:-30", 0},
+		{"NaN", 0},
+		{"Inf", 0},
+	}
+	for _, tt := range tests {
+		if got := feedDuration(tt.in); got != tt.want {
+			t.Errorf("feedDuration(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFeedPubDate(t *testing.T) {
+	may5 := time.Date(2025, 5, 5, 7, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"Mon, 05 May 2025 07:00:00 +0000", may5, true},
+		{"Mon, 5 May 2025 07:00:00 +0000", may5, true},
+		{"Mon, 05 May 2025 09:00:00 +0200", may5, true},
+		{"Sun, 04 May 2025 23:00:00 -0800", may5, true},
+		{"5 May 2025 07:00:00 +0000", may5, true},
+		{"2025-05-05T07:00:00Z", may5, true},
+		{"2025-05-05T09:00:00+02:00", may5, true},
+		{"2025-05-05T07:00:00.500Z", may5.Add(500 * time.Millisecond), true},
+		{" Mon, 05 May 2025 07:00:00 GMT ", may5, true},
+		{"Mon, 5 May 2025 07:00:00 UTC", may5, true},
+		{"", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+		{"2025-05-05", time.Time{}, false},
+		{"05/05/2025 07:00", time.Time{}, false},
+		{"Mon, 32 May 2025 07:00:00 +0000", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := feedPubDate(tt.in)
+		if ok != tt.ok || (ok && !got.Equal(tt.want)) {
+			t.Errorf("feedPubDate(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestInFeedsDir(t *testing.T) {
+	dir := t.TempDir()
+	defer func(old string) { importFeedsDir = old }(importFeedsDir)
+	importFeedsDir = filepath.Join(dir, "feeds")
+	if err := os.MkdirAll(importFeedsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	inside := filepath.Join(importFeedsDir, "show.xml")
+	outside := filepath.Join(dir, "secret.xml")
+	for _, f := range []string{inside, outside} {
+		if err := os.WriteFile(f, []byte("<rss/>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(importFeedsDir, "link.xml")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		inside: true,
+		filepath.Join(importFeedsDir, "..", "secret.xml"): false,
+		outside: false,
+		link:    false,
+		filepath.Join(importFeedsDir, "missing.xml"): false,
+	}
+	for source, want := range tests {
+		if got := inFeedsDir(source); got != want {
+			t.Errorf("inFeedsDir(%q) = %v, want %v", source, got, want)
+		}
+	}
+}
@@ -22,11 +22,16 @@ type AlbumResponse struct {
 	Title   string `json:"title"`
 	Artist  string `json:"artist"`
 	Cover   string `json:"cover"`
+
+	AlbumType   string `json:"album_type,omitempty"`
+	ReleaseDate string `json:"release_date,omitempty"`
 }
 
 // GetSearch handles GET /search?q=foo
 func GetSearch(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
 		q := strings.TrimSpace(c.Query("q"))
 		if q == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "q query param required"})
@@ -36,9 +41,15 @@ func GetSearch(db *gorm.DB) gin.HandlerFunc {
 
 		// 1) Search Songs
 		var songs []models.Song
+		// a song matches through its billed artist or any credited performer
+		credited := db.
+			Model(&models.SongCredit{}).
+			Select("song_credits.song_id").
+			Joins("JOIN artists ON artists.artist_id = song_credits.artist_id").
+			Where("song_credits.role IN ? AND artists.name LIKE ?", models.PerformerRoles, wildcard)
 		db.Preload("Artist"). // so s.Artist is populated in your response
 					Joins("LEFT JOIN artists ON artists.artist_id = songs.artist_id").
-					Where("songs.title LIKE ? OR artists.name LIKE ?", wildcard, wildcard).
+					Where("songs.title LIKE ? OR artists.name LIKE ? OR songs.id IN (?)", wildcard, wildcard, credited).
 					Find(&songs)
 		tracks := make([]models.TrackResponse, len(songs))
 		for i, s := range songs {
@@ -50,6 +61,16 @@ func GetSearch(db *gorm.DB) gin.HandlerFunc {
 				AlbumArt: "/media/album-art.jpg",
 			}
 		}
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(tracks)
+		if err := loadTrackArtists(db, tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
 
 		// 2) Search Artists
 		var artists []models.Artist
@@ -65,15 +86,10 @@ func GetSearch(db *gorm.DB) gin.HandlerFunc {
 
 		// 3) Search Albums
 		var albums []models.Album
-		db.Where("title LIKE ?", wildcard).Find(&albums)
+		db.Preload("Artist").Where("title LIKE ?", wildcard).Find(&albums)
 		albumRes := make([]AlbumResponse, len(albums))
 		for i, al := range albums {
-			albumRes[i] = AlbumResponse{
-				AlbumID: al.AlbumId,
-				Title:   al.Title,
-				Artist:  al.Artist.Name,
-				Cover:   al.Cover,
-			}
+			albumRes[i] = newAlbumResponse(al)
 		}
 
 		// 4) Search Playlists
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"spotify-mock-api/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// statsRange describes one ?time_range= value: how far back plays count
+// (0 = all time) and the half-life used to fade older plays.
+type statsRange struct {
+	window   time.Duration
+	halfLife time.Duration
+}
+
+const day = 24 * time.Hour
+
+var statsRanges = map[string]statsRange{
+	"short_term":  {window: 28 * day, halfLife: 7 * day},
+	"medium_term": {window: 182 * day, halfLife: 30 * day},
+	"long_term":   {window: 0, halfLife: 365 * day},
+}
+
+// TopGenreResponse is one entry of GET /me/top/genres
+type TopGenreResponse struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	Share float64 `json:"share"` // fraction of all listening, 0..1
+}
+
+// listeningStats holds per-track, per-artist and per-genre scores built
+// from a user's play history.
+type listeningStats struct {
+	songs      map[int]models.Song
+	artistRows map[int]models.Artist // every artist in artists
+	tracks     map[int]float64
+	artists    map[int]float64
+	genres     map[string]float64
+	total      float64
+}
+
+// playWeight scores one play: a full listen counts 1, partial listens
+// count the fraction heard, and older plays fade by halfLife (0 = no fade).
+func playWeight(p models.RecentPlay, durationSec int, now time.Time, halfLife time.Duration) float64 {
+	w := 1.0
+	// rows recorded before ms_played existed count as full plays
+	if p.MsPlayed > 0 && durationSec > 0 {
+		w = math.Min(float64(p.MsPlayed)/float64(durationSec*1000), 1)
+	}
+	if halfLife > 0 {
+		age := now.Sub(p.PlayedAt)
+		if age > 0 {
+			w *= math.Pow(0.5, float64(age)/float64(halfLife))
+		}
+	}
+	return w
+}
+
+// loadListeningStats scores the user's track plays in [from, to). A zero
+// from means "since the beginning"; a zero to means "until now".
+func loadListeningStats(db *gorm.DB, userID int, from, to time.Time, halfLife time.Duration) (listeningStats, error) {
+	stats := listeningStats{
+		songs:      make(map[int]models.Song),
+		artistRows: make(map[int]models.Artist),
+		tracks:     make(map[int]float64),
+		artists:    make(map[int]float64),
+		genres:     make(map[string]float64),
+	}
+
+	now := time.Now()
+	if to.IsZero() {
+		to = now
+	}
+	q := db.Where("user_id = ? AND item_type = ? AND played_at < ?", userID, "track", to)
+	if !from.IsZero() {
+		q = q.Where("played_at >= ?", from)
+	}
+	var plays []models.RecentPlay
+	if err := q.Find(&plays).Error; err != nil {
+		return stats, err
+	}
+
+	ids := make([]int, 0, len(plays))
+	for _, p := range plays {
+		ids = append(ids, p.ReferenceID)
+	}
+	var songs []models.Song
+	if len(ids) > 0 {
+		if err := db.
+			Preload("Artist").
+			Preload("Album").
+			Preload("GenreTags").
+			Where("id IN ?", ids).
+			Find(&songs).
+			Error; err != nil {
+			return stats, err
+		}
+	}
+	for _, s := range songs {
+		stats.songs[s.ID] = s
+	}
+	// every performer gets the play, featured artists included; songs
+	// without credits count for their billed artist
+	performers := make(map[int][]int)
+	if len(ids) > 0 {
+		var credits []models.SongCredit
+		if err := db.
+			Where("song_id IN ? AND role IN ?", ids, models.PerformerRoles).
+			Find(&credits).
+			Error; err != nil {
+			return stats, err
+		}
+		for _, c := range credits {
+			performers[c.SongID] = append(performers[c.SongID], c.ArtistID)
+		}
+	}
+
+	for _, p := range plays {
+		s, ok := stats.songs[p.ReferenceID]
+		if !ok {
+			continue
+		}
+		w := playWeight(p, s.Duration, to, halfLife)
+		stats.tracks[s.ID] += w
+		artists, ok := performers[s.ID]
+		if !ok {
+			artists = []int{s.ArtistID}
+		}
+		for _, a := range artists {
+			stats.artists[a] += w
+		}
+		for _, g := range songGenres(s) {
+			stats.genres[g] += w
+		}
+		stats.total += w
+	}
+
+	if len(stats.artists) > 0 {
+		var rows []models.Artist
+		if err := db.Where("artist_id IN ?", rankIDs(stats.artists)).Find(&rows).Error; err != nil {
+			return stats, err
+		}
+		for _, a := range rows {
+			stats.artistRows[a.ArtistId] = a
+		}
+	}
+	return stats, nil
+}
+
+// songGenres returns the names of the song's catalog genres; GenreTags
+// must be preloaded.
+func songGenres(s models.Song) []string {
+	genres := make([]string, len(s.GenreTags))
+	for i, g := range s.GenreTags {
+		genres[i] = g.Name
+	}
+	return genres
+}
+
+// rankIDs returns the keys of scores, highest score first, ties by ID.
+func rankIDs(scores map[int]float64) []int {
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// rankNames is rankIDs for string keys.
+func rankNames(scores map[string]float64) []string {
+	names := make([]string, 0, len(scores))
+	for n := range scores {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if scores[names[i]] != scores[names[j]] {
+			return scores[names[i]] > scores[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// statsForRequest reads ?time_range= (default medium_term) and loads the
+// matching stats, writing the error response itself on failure.
+func statsForRequest(c *gin.Context, db *gorm.DB, userID int) (listeningStats, bool) {
+	rng, ok := statsRanges[c.DefaultQuery("time_range", "medium_term")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time_range must be short_term, medium_term or long_term"})
+		return listeningStats{}, false
+	}
+	var from time.Time
+	if rng.window > 0 {
+		from = time.Now().Add(-rng.window)
+	}
+	stats, err := loadListeningStats(db, userID, from, time.Time{}, rng.halfLife)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load listening history"})
+		return stats, false
+	}
+	return stats, true
+}
+
+// GET /me/top/artists?time_range=short_term&limit=20&offset=0
+func GetTopArtists(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+		stats, ok := statsForRequest(c, db, userID)
+		if !ok {
+			return
+		}
+
+		ranked := rankIDs(stats.artists)
+		start, end := pageBounds(len(ranked), limit, offset)
+
+		items := make([]ArtistResponse, 0, end-start)
+		for _, id := range ranked[start:end] {
+			a := stats.artistRows[id]
+			items = append(items, ArtistResponse{
+				ID:    a.ArtistId,
+				Name:  a.Name,
+				Image: a.Image,
+			})
+		}
+		c.JSON(http.StatusOK, newPagingResponse(c, items, len(ranked), limit, offset))
+	}
+}
+
+// GET /me/top/tracks?time_range=short_term&limit=20&offset=0
+func GetTopTracks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+		stats, ok := statsForRequest(c, db, userID)
+		if !ok {
+			return
+		}
+
+		ranked := rankIDs(stats.tracks)
+		start, end := pageBounds(len(ranked), limit, offset)
+
+		items := make([]models.TrackResponse, 0, end-start)
+		for _, id := range ranked[start:end] {
+			items = append(items, newTrackResponse(stats.songs[id]))
+		}
+
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(items)
+		if err := loadTrackArtists(db, items); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, newPagingResponse(c, items, len(ranked), limit, offset))
+	}
+}
+
+// GET /me/top/genres?time_range=long_term
+// Genres come from the genre catalog; a play counts towards every genre of
+// its song, so shares can add up to more than 1.
+func GetTopGenres(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		limit, offset, ok := parsePaging(c, 20, 50)
+		if !ok {
+			return
+		}
+		stats, ok := statsForRequest(c, db, userID)
+		if !ok {
+			return
+		}
+
+		ranked := rankNames(stats.genres)
+		start, end := pageBounds(len(ranked), limit, offset)
+
+		items := make([]TopGenreResponse, 0, end-start)
+		for _, name := range ranked[start:end] {
+			items = append(items, TopGenreResponse{
+				Name:  name,
+				Score: math.Round(stats.genres[name]*100) / 100,
+				Share: math.Round(stats.genres[name]/stats.total*1000) / 1000,
+			})
+		}
+		c.JSON(http.StatusOK, newPagingResponse(c, items, len(ranked), limit, offset))
+	}
+}
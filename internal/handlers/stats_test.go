@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"math"
+	"spotify-mock-api/internal/models"
+	"testing"
+	"time"
+)
+
+func TestPlayWeight(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		msPlayed int
+		duration int
+		age      time.Duration
+		halfLife time.Duration
+		want     float64
+	}{
+		{"full listen", 200000, 200, 0, 0, 1},
+		{"half listen", 100000, 200, 0, 0, 0.5},
+		{"more than the track is capped", 500000, 200, 0, 0, 1},
+		{"no ms_played counts as full", 0, 200, 0, 0, 1},
+		{"unknown duration counts as full", 30000, 0, 0, 0, 1},
+		{"one half-life ago", 200000, 200, 7 * day, 7 * day, 0.5},
+		{"two half-lives ago, half heard", 100000, 200, 14 * day, 7 * day, 0.125},
+		{"no fade without a half-life", 200000, 200, 365 * day, 0, 1},
+		{"plays from the future don't grow", 200000, 200, -day, 7 * day, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := models.RecentPlay{MsPlayed: tt.msPlayed, PlayedAt: now.Add(-tt.age)}
+			if got := playWeight(p, tt.duration, now, tt.halfLife); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("playWeight = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadListeningStatsCreditsEveryPerformer(t *testing.T) {
+	db := newTestDB(t, &models.Artist{}, &models.Album{}, &models.Genre{}, &models.Song{},
+		&models.SongCredit{}, &models.RecentPlay{})
+	rows := []interface{}{
+		&models.Artist{ArtistId: 1, Name: "Drake"},
+		&models.Artist{ArtistId: 2, Name: "Wizkid"},
+		&models.Artist{ArtistId: 3, Name: "Solo"},
+		&models.Song{ID: 10, Title: "One Dance", ArtistID: 1, Duration: 100},
+		&models.Song{ID: 11, Title: "Alone", ArtistID: 3, Duration: 100},
+		&models.SongCredit{SongID: 10, ArtistID: 1, Role: models.RolePrimary},
+		&models.SongCredit{SongID: 10, ArtistID: 2, Role: models.RoleFeatured},
+		&models.SongCredit{SongID: 10, ArtistID: 3, Role: models.RoleProducer},
+		&models.RecentPlay{UserID: 1, Type: "track", ItemType: "track", ReferenceID: 10, MsPlayed: 100000},
+		&models.RecentPlay{UserID: 1, Type: "track", ItemType: "track", ReferenceID: 11, MsPlayed: 50000},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := loadListeningStats(db, 1, time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]float64{1: 1, 2: 1, 3: 0.5} // producers don't perform
+	for id, w := range want {
+		if got := stats.artists[id]; math.Abs(got-w) > 1e-9 {
+			t.Errorf("artist %d scored %v, want %v", id, got, w)
+		}
+		if stats.artistRows[id].ArtistId != id {
+			t.Errorf("artist %d not loaded", id)
+		}
+	}
+	if len(stats.artists) != len(want) {
+		t.Errorf("scored artists %v, want %v", stats.artists, want)
+	}
+}
@@ -34,22 +34,7 @@ func (h *TrackHandler) GetTrackByID(c *gin.Context) {
 	// build your audio URL
 	audioURL := "/media/song.mp3"
 
-	// read where this play came from:
-	// e.g. GET /tracks/123?origin=playlist&originId=42
-	originType := c.Query("origin")
-	originID, _ := strconv.Atoi(c.Query("originId"))
-	playType := "track"
-	if originType != "" {
-		playType = originType
-	}
-
-	// record the play, including its origin
-	h.DB.Create(&models.RecentPlay{
-		UserID:      1,
-		Type:        playType,
-		ReferenceID: id,
-		OriginID:    originID,
-	})
+	// plays are reported through POST /me/plays; fetching metadata is read-only
 	response := models.TrackResponse{
 		ID:       song.ID,
 		Title:    song.Title,
@@ -61,11 +46,53 @@ func (h *TrackHandler) GetTrackByID(c *gin.Context) {
 		Duration: song.Duration,
 		AudioURL: audioURL,
 		Color:    "#303549",
+
+		DiscNumber:  song.DiscNumber,
+		TrackNumber: song.TrackNumber,
+	}
+
+	downloads, err := loadDownloads(h.DB, 1, deviceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+		return
 	}
+	response.Downloaded = downloads.tracks[song.ID]
+
+	tracks := []models.TrackResponse{response}
+	if err := loadTrackArtists(h.DB, tracks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+		return
+	}
+	response = tracks[0]
+
 	// return the metadata
 	c.JSON(http.StatusOK, response)
 }
 
+// newTrackResponse maps a song (with Artist and Album preloaded) to the
+// TrackResponse shape used by every listing endpoint.
+func newTrackResponse(s models.Song) models.TrackResponse {
+	audioURL := s.AudioURL
+	if audioURL == "" {
+		audioURL = "/media/song.mp3"
+	}
+	return models.TrackResponse{
+		ID:       s.ID,
+		Title:    s.Title,
+		Artist:   s.Artist.Name,
+		ArtistID: s.ArtistID,
+		AudioURL: audioURL,
+		AlbumArt: s.Album.Cover,
+		AlbumID:  s.AlbumID,
+		Album:    s.Album.Title,
+		Duration: s.Duration,
+		Color:    s.Color,
+
+		DiscNumber:  s.DiscNumber,
+		TrackNumber: s.TrackNumber,
+	}
+}
+
 // getTrackAudio streams the MP3 file (always the same file)
 func GetTrackAudio(c *gin.Context) {
 	c.File(filepath.Join("media", "song.mp3"))
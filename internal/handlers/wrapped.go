@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"spotify-mock-api/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	wrappedTopN          = 5
+	wrappedPlaylistLimit = 100
+)
+
+// WrappedPodcast is one of the year's most-listened shows.
+type WrappedPodcast struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Cover   string `json:"cover"`
+	Minutes int    `json:"minutes"`
+}
+
+// WrappedStreak is the longest run of consecutive days with at least one play.
+type WrappedStreak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start,omitempty"` // YYYY-MM-DD
+	End   string `json:"end,omitempty"`
+}
+
+// WrappedDay is the single day with the most listening.
+type WrappedDay struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Minutes int    `json:"minutes"`
+}
+
+// WrappedPlaylist is the generated "Your Top Songs" playlist. PlaylistID is
+// set once it has been saved with POST /me/wrapped/:year/playlist.
+type WrappedPlaylist struct {
+	Title      string                 `json:"title"`
+	PlaylistID *int                   `json:"playlist_id"`
+	Tracks     []models.TrackResponse `json:"tracks"`
+}
+
+// WrappedReport is the payload for GET /me/wrapped/:year
+type WrappedReport struct {
+	Year          int                    `json:"year"`
+	TotalMinutes  int                    `json:"total_minutes"`
+	TotalPlays    int                    `json:"total_plays"`
+	TopArtists    []ArtistResponse       `json:"top_artists"`
+	TopTracks     []models.TrackResponse `json:"top_tracks"`
+	TopGenres     []TopGenreResponse     `json:"top_genres"`
+	TopPodcasts   []WrappedPodcast       `json:"top_podcasts"`
+	LongestStreak WrappedStreak          `json:"longest_streak"`
+	MostPlayedDay *WrappedDay            `json:"most_played_day"`
+	TopSongs      WrappedPlaylist        `json:"top_songs_playlist"`
+}
+
+// GET /me/wrapped/:year
+func GetWrapped(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		year, err := strconv.Atoi(c.Param("year"))
+		if err != nil || year < 1970 || year > time.Now().Year() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+
+		report, err := buildWrapped(db, userID, year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not build report"})
+			return
+		}
+		if report.TotalPlays == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no listening history for %d", year)})
+			return
+		}
+
+		downloads, err := loadDownloads(db, userID, deviceID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load downloads"})
+			return
+		}
+		downloads.markDownloaded(report.TopTracks)
+		downloads.markDownloaded(report.TopSongs.Tracks)
+		if err := loadTrackArtists(db, report.TopTracks, report.TopSongs.Tracks); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot load track artists"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// POST /me/wrapped/:year/playlist
+// Saves the year's top songs as a playlist owned by the user. Calling it
+// again refreshes the same playlist instead of creating another one.
+func SaveWrappedPlaylist(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const userID = 1 // TODO: replace with real auth
+
+		year, err := strconv.Atoi(c.Param("year"))
+		if err != nil || year < 1970 || year > time.Now().Year() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+
+		report, err := buildWrapped(db, userID, year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not build report"})
+			return
+		}
+		if len(report.TopSongs.Tracks) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no listening history for %d", year)})
+			return
+		}
+
+		var pl models.Playlist
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.
+				Where(models.Playlist{UserID: userID, Title: report.TopSongs.Title}).
+				Attrs(models.Playlist{Cover: "/media/playlist-art.jpg"}).
+				FirstOrCreate(&pl).
+				Error; err != nil {
+				return err
+			}
+			if err := tx.
+				Where("playlist_id = ?", pl.ID).
+				Delete(&models.PlaylistSong{}).
+				Error; err != nil {
+				return err
+			}
+			for rank, t := range report.TopSongs.Tracks {
+				if err := tx.
+					Clauses(clause.OnConflict{DoNothing: true}).
+					Create(&models.PlaylistSong{PlaylistID: pl.ID, SongID: t.ID, Position: rank + 1}).
+					Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&pl).Updates(map[string]interface{}{
+				"generator":    generatorWrapped,
+				"last_updated": time.Now(),
+			}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save playlist"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, PlaylistResponse{
+			ID:          pl.ID,
+			Title:       pl.Title,
+			Subtitle:    fmt.Sprintf("Playlist • %d tracks", len(report.TopSongs.Tracks)),
+			Cover:       pl.Cover,
+			LastUpdated: pl.LastUpdated,
+		})
+	}
+}
+
+// buildWrapped aggregates the user's plays during the calendar year.
+func buildWrapped(db *gorm.DB, userID, year int) (WrappedReport, error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(1, 0, 0)
+	report := WrappedReport{
+		Year:     year,
+		TopSongs: WrappedPlaylist{Title: fmt.Sprintf("Your Top Songs %d", year)},
+	}
+
+	// tracks, artists and genres: whole year, no recency fade
+	stats, err := loadListeningStats(db, userID, from, to, 0)
+	if err != nil {
+		return report, err
+	}
+
+	for _, id := range firstN(rankIDs(stats.artists), wrappedTopN) {
+		a := stats.artistRows[id]
+		report.TopArtists = append(report.TopArtists, ArtistResponse{ID: a.ArtistId, Name: a.Name, Image: a.Image})
+	}
+	for i, id := range firstN(rankIDs(stats.tracks), wrappedPlaylistLimit) {
+		t := newTrackResponse(stats.songs[id])
+		if i < wrappedTopN {
+			report.TopTracks = append(report.TopTracks, t)
+		}
+		report.TopSongs.Tracks = append(report.TopSongs.Tracks, t)
+	}
+	for _, name := range firstNames(rankNames(stats.genres), wrappedTopN) {
+		report.TopGenres = append(report.TopGenres, TopGenreResponse{
+			Name:  name,
+			Score: math.Round(stats.genres[name]*100) / 100,
+			Share: math.Round(stats.genres[name]/stats.total*1000) / 1000,
+		})
+	}
+
+	// minutes, days and podcasts need every play, episodes included
+	var plays []models.RecentPlay
+	if err := db.
+		Where("user_id = ? AND played_at >= ? AND played_at < ?", userID, from, to).
+		Order("played_at").
+		Find(&plays).
+		Error; err != nil {
+		return report, err
+	}
+	var episodeIDs []int
+	for _, p := range plays {
+		if p.ItemType == "episode" {
+			episodeIDs = append(episodeIDs, p.ReferenceID)
+		}
+	}
+	episodeSecs, err := episodeDurations(db, episodeIDs)
+	if err != nil {
+		return report, err
+	}
+
+	podcasts := make(map[int]models.Podcast)
+	dayMs := make(map[string]int)
+	showMs := make(map[int]int)
+	totalMs := 0
+	// plays of shows or tracks that are gone count for neither plays nor
+	// minutes
+	for _, p := range plays {
+		ms := p.MsPlayed
+		if p.ItemType == "episode" {
+			if _, ok := podcasts[p.OriginID]; !ok {
+				var pod models.Podcast
+				if err := db.First(&pod, p.OriginID).Error; err != nil {
+					continue
+				}
+				podcasts[p.OriginID] = pod
+			}
+			if ms == 0 {
+				ms = episodeSecs[p.ReferenceID] * 1000
+			}
+			showMs[p.OriginID] += ms
+		} else {
+			s, ok := stats.songs[p.ReferenceID]
+			if !ok {
+				continue
+			}
+			if ms == 0 {
+				// rows recorded before ms_played existed count as full plays
+				ms = s.Duration * 1000
+			}
+		}
+		report.TotalPlays++
+		totalMs += ms
+		dayMs[p.PlayedAt.In(time.Local).Format("2006-01-02")] += ms
+	}
+	report.TotalMinutes = totalMs / 60000
+
+	showScores := make(map[int]float64, len(showMs))
+	for id, ms := range showMs {
+		showScores[id] = float64(ms)
+	}
+	for _, id := range firstN(rankIDs(showScores), wrappedTopN) {
+		p := podcasts[id]
+		report.TopPodcasts = append(report.TopPodcasts, WrappedPodcast{
+			ID:      p.ID,
+			Title:   p.Title,
+			Cover:   p.Cover,
+			Minutes: showMs[id] / 60000,
+		})
+	}
+
+	days := make([]string, 0, len(dayMs))
+	for d := range dayMs {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+	bestMs := -1
+	for _, d := range days {
+		if dayMs[d] > bestMs {
+			bestMs = dayMs[d]
+			report.MostPlayedDay = &WrappedDay{Date: d, Minutes: dayMs[d] / 60000}
+		}
+	}
+	report.LongestStreak = longestStreak(days)
+
+	var saved models.Playlist
+	if err := db.
+		Where("user_id = ? AND title = ?", userID, report.TopSongs.Title).
+		First(&saved).
+		Error; err == nil {
+		report.TopSongs.PlaylistID = &saved.ID
+	}
+	return report, nil
+}
+
+// longestStreak finds the longest run of consecutive dates in a sorted
+// list of YYYY-MM-DD strings.
+func longestStreak(days []string) WrappedStreak {
+	var best, cur WrappedStreak
+	var prev time.Time
+	for _, d := range days {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			continue
+		}
+		if cur.Days > 0 && t.Sub(prev) == day {
+			cur.Days++
+			cur.End = d
+		} else {
+			cur = WrappedStreak{Days: 1, Start: d, End: d}
+		}
+		if cur.Days > best.Days {
+			best = cur
+		}
+		prev = t
+	}
+	return best
+}
+
+// episodeDurations looks up the length in seconds of each episode.
+func episodeDurations(db *gorm.DB, ids []int) (map[int]int, error) {
+	secs := make(map[int]int, len(ids))
+	if len(ids) == 0 {
+		return secs, nil
+	}
+	var episodes []models.PodcastEpisode
+	if err := db.Select("id", "duration").Where("id IN ?", ids).Find(&episodes).Error; err != nil {
+		return nil, err
+	}
+	for _, ep := range episodes {
+		secs[ep.ID] = ep.Duration
+	}
+	return secs, nil
+}
+
+func firstN(ids []int, n int) []int {
+	if len(ids) > n {
+		return ids[:n]
+	}
+	return ids
+}
+
+func firstNames(names []string, n int) []string {
+	if len(names) > n {
+		return names[:n]
+	}
+	return names
+}
@@ -13,4 +13,16 @@ type TrackResponse struct {
 	Duration   int    `json:"duration,omitempty"` // in seconds
 	Color      string `json:"color,omitempty"`    // hex color code for UI
 	Genres     string `json:"genres,omitempty"`
+
+	DiscNumber  int `json:"disc_number,omitempty"`
+	TrackNumber int `json:"track_number,omitempty"`
+
+	Artists []TrackArtist `json:"artists" gorm:"-"` // every performing artist, primary first
+}
+
+// TrackArtist is one credited artist in a TrackResponse.
+type TrackArtist struct {
+	ID   int    `json:"id"` // 0 for writers and producers who aren't artists in the catalog
+	Name string `json:"name"`
+	Role string `json:"role"` // "primary" | "featured", or "writer" | "producer" in credits
 }
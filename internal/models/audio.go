@@ -0,0 +1,229 @@
+package models
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// AudioFeatures holds Spotify-style audio features for one song.
+type AudioFeatures struct {
+	SongID           int     `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	Danceability     float64 `json:"danceability"`
+	Energy           float64 `json:"energy"`
+	Key              int     `json:"key"` // pitch class, 0 = C … 11 = B
+	Loudness         float64 `json:"loudness"`
+	Mode             int     `json:"mode"` // 1 = major, 0 = minor
+	Speechiness      float64 `json:"speechiness"`
+	Acousticness     float64 `json:"acousticness"`
+	Instrumentalness float64 `json:"instrumentalness"`
+	Liveness         float64 `json:"liveness"`
+	Valence          float64 `json:"valence"`
+	Tempo            float64 `json:"tempo"`
+	DurationMs       int     `json:"duration_ms"`
+	TimeSignature    int     `json:"time_signature"`
+}
+
+// AudioAnalysis holds the rhythmic structure of one song. Each column is
+// a JSON array of AnalysisInterval (Sections of AnalysisSection).
+type AudioAnalysis struct {
+	SongID   int            `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Bars     datatypes.JSON `json:"bars"`
+	Beats    datatypes.JSON `json:"beats"`
+	Tatums   datatypes.JSON `json:"tatums"`
+	Sections datatypes.JSON `json:"sections"`
+}
+
+// AnalysisInterval is one bar, beat or tatum, in seconds.
+type AnalysisInterval struct {
+	Start      float64 `json:"start"`
+	Duration   float64 `json:"duration"`
+	Confidence float64 `json:"confidence"`
+}
+
+// AnalysisSection is a larger part of a song, such as a verse or chorus.
+type AnalysisSection struct {
+	AnalysisInterval
+	Loudness      float64 `json:"loudness"`
+	Tempo         float64 `json:"tempo"`
+	Key           int     `json:"key"`
+	Mode          int     `json:"mode"`
+	TimeSignature int     `json:"time_signature"`
+}
+
+// genreProfile is the typical sound of a genre; generated features are
+// scattered around it.
+type genreProfile struct {
+	danceability, energy, valence, acousticness float64
+	speechiness, instrumentalness               float64
+	tempo, loudness                             float64
+	minorChance                                 float64
+}
+
+// genreProfiles is matched against a song's genres by substring, most
+// specific first.
+var genreProfiles = []struct {
+	match   string
+	profile genreProfile
+}{
+	{"metal", genreProfile{0.40, 0.92, 0.30, 0.01, 0.08, 0.20, 140, -4.5, 0.65}},
+	{"punk", genreProfile{0.45, 0.90, 0.50, 0.02, 0.07, 0.05, 160, -4.8, 0.45}},
+	{"grunge", genreProfile{0.40, 0.82, 0.30, 0.05, 0.05, 0.10, 120, -5.5, 0.60}},
+	{"hard rock", genreProfile{0.45, 0.85, 0.45, 0.03, 0.06, 0.08, 130, -5.2, 0.45}},
+	{"soft rock", genreProfile{0.55, 0.50, 0.55, 0.35, 0.03, 0.05, 105, -8.5, 0.35}},
+	{"progressive", genreProfile{0.35, 0.60, 0.35, 0.20, 0.04, 0.45, 110, -9.5, 0.55}},
+	{"hip hop", genreProfile{0.78, 0.65, 0.50, 0.10, 0.25, 0.01, 95, -6.0, 0.55}},
+	{"r&b", genreProfile{0.70, 0.55, 0.50, 0.20, 0.10, 0.02, 100, -6.8, 0.50}},
+	{"soul", genreProfile{0.62, 0.55, 0.65, 0.35, 0.05, 0.03, 105, -8.0, 0.35}},
+	{"funk", genreProfile{0.80, 0.70, 0.80, 0.10, 0.06, 0.10, 112, -7.0, 0.40}},
+	{"country", genreProfile{0.60, 0.60, 0.60, 0.30, 0.04, 0.01, 120, -6.5, 0.20}},
+	{"latin", genreProfile{0.75, 0.75, 0.70, 0.15, 0.08, 0.01, 100, -5.0, 0.40}},
+	{"indie", genreProfile{0.58, 0.55, 0.45, 0.30, 0.04, 0.15, 118, -7.5, 0.45}},
+	{"alternative", genreProfile{0.55, 0.65, 0.40, 0.15, 0.05, 0.10, 122, -6.5, 0.50}},
+	{"britpop", genreProfile{0.50, 0.75, 0.50, 0.08, 0.04, 0.05, 125, -5.5, 0.40}},
+	{"rock", genreProfile{0.50, 0.75, 0.50, 0.08, 0.05, 0.08, 125, -6.0, 0.40}},
+	{"pop", genreProfile{0.68, 0.68, 0.55, 0.15, 0.06, 0.01, 118, -5.5, 0.40}},
+}
+
+var defaultProfile = genreProfile{0.60, 0.60, 0.50, 0.20, 0.06, 0.05, 118, -7.0, 0.40}
+
+// profileFor picks the profile of the first genre that matches one.
+func profileFor(genres []string) (genreProfile, string) {
+	for _, g := range genres {
+		lg := strings.ToLower(g)
+		for _, p := range genreProfiles {
+			if strings.Contains(lg, p.match) {
+				return p.profile, lg
+			}
+		}
+	}
+	return defaultProfile, ""
+}
+
+// songRand returns a random source seeded from the song ID and genre, so
+// the same song always gets the same values.
+func songRand(songID int, genre string, salt string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(genre + "|" + salt))
+	return rand.New(rand.NewSource(int64(songID)*1_000_003 ^ int64(h.Sum64())))
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// round rounds v to n decimals, so generated values look like Spotify's.
+func round(v float64, n int) float64 {
+	p := math.Pow(10, float64(n))
+	return math.Round(v*p) / p
+}
+
+// GenerateAudioFeatures derives plausible audio features from the song's
+// ID and genres (GenreTags, which must be preloaded). The result is
+// deterministic.
+func GenerateAudioFeatures(s Song) AudioFeatures {
+	genres := make([]string, len(s.GenreTags))
+	for i, g := range s.GenreTags {
+		genres[i] = g.Name
+	}
+	sort.Strings(genres) // preload order isn't stable
+	p, genre := profileFor(genres)
+	r := songRand(s.ID, genre, "features")
+	jitter := func(base, spread float64) float64 {
+		return base + (r.Float64()*2-1)*spread
+	}
+
+	mode := 1
+	if r.Float64() < p.minorChance {
+		mode = 0
+	}
+	timeSignature := 4
+	if r.Float64() < 0.08 {
+		timeSignature = 3
+	}
+
+	return AudioFeatures{
+		SongID:           s.ID,
+		Danceability:     round(clamp01(jitter(p.danceability, 0.15)), 3),
+		Energy:           round(clamp01(jitter(p.energy, 0.15)), 3),
+		Key:              r.Intn(12),
+		Loudness:         round(math.Min(-1, jitter(p.loudness, 2.5)), 3),
+		Mode:             mode,
+		Speechiness:      round(clamp01(jitter(p.speechiness, 0.04)), 4),
+		Acousticness:     round(clamp01(jitter(p.acousticness, 0.12)), 4),
+		Instrumentalness: round(clamp01(p.instrumentalness*r.Float64()*2), 6),
+		Liveness:         round(clamp01(0.05+r.ExpFloat64()*0.1), 4),
+		Valence:          round(clamp01(jitter(p.valence, 0.2)), 3),
+		Tempo:            round(jitter(p.tempo, 18), 3),
+		DurationMs:       s.Duration * 1000,
+		TimeSignature:    timeSignature,
+	}
+}
+
+// GenerateAudioAnalysis lays out bars, beats, tatums and sections that
+// agree with the song's features. The result is deterministic.
+func GenerateAudioAnalysis(f AudioFeatures) (AudioAnalysis, error) {
+	r := songRand(f.SongID, "", "analysis")
+	length := float64(f.DurationMs) / 1000
+	beatLen := 60 / f.Tempo
+	timeSignature := f.TimeSignature
+	if timeSignature < 1 {
+		timeSignature = 4
+	}
+
+	beats, tatums, bars := []AnalysisInterval{}, []AnalysisInterval{}, []AnalysisInterval{}
+	for i, start := 0, 0.0; f.Tempo > 0 && start+beatLen <= length; i, start = i+1, start+beatLen {
+		beats = append(beats, AnalysisInterval{round(start, 5), round(beatLen, 5), round(0.3+r.Float64()*0.7, 3)})
+		for t := 0; t < 2; t++ {
+			tatums = append(tatums, AnalysisInterval{round(start+float64(t)*beatLen/2, 5), round(beatLen/2, 5), round(r.Float64(), 3)})
+		}
+		if i%timeSignature == 0 {
+			barLen := math.Min(beatLen*float64(timeSignature), length-start)
+			bars = append(bars, AnalysisInterval{round(start, 5), round(barLen, 5), round(0.2+r.Float64()*0.8, 3)})
+		}
+	}
+
+	// split the song into 5–9 sections on bar boundaries
+	sections := []AnalysisSection{}
+	if n := len(bars); n > 0 {
+		count := 5 + r.Intn(5)
+		if count > n {
+			count = n
+		}
+		for i := 0; i < count; i++ {
+			first, last := i*n/count, (i+1)*n/count
+			start := bars[first].Start
+			end := length
+			if last < n {
+				end = bars[last].Start
+			}
+			sections = append(sections, AnalysisSection{
+				AnalysisInterval: AnalysisInterval{round(start, 5), round(end-start, 5), round(r.Float64(), 3)},
+				Loudness:         round(f.Loudness+(r.Float64()*2-1)*3, 3),
+				Tempo:            round(f.Tempo+(r.Float64()*2-1)*1.5, 3),
+				Key:              f.Key,
+				Mode:             f.Mode,
+				TimeSignature:    f.TimeSignature,
+			})
+		}
+	}
+
+	a := AudioAnalysis{SongID: f.SongID}
+	var err error
+	if a.Bars, err = json.Marshal(bars); err != nil {
+		return a, err
+	}
+	if a.Beats, err = json.Marshal(beats); err != nil {
+		return a, err
+	}
+	if a.Tatums, err = json.Marshal(tatums); err != nil {
+		return a, err
+	}
+	a.Sections, err = json.Marshal(sections)
+	return a, err
+}
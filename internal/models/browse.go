@@ -0,0 +1,17 @@
+package models
+
+// Category is a tile of the Browse grid, grouping editorial playlists.
+type Category struct {
+	ID          string     `json:"id" gorm:"primaryKey"` // slug, e.g. "hiphop"
+	Name        string     `json:"name"`
+	Icon        string     `json:"icon"`
+	Color       string     `json:"color"`    // tile background, hex
+	Position    int        `json:"position"` // order in the grid
+	Playlists   []Playlist `json:"-" gorm:"many2many:category_playlists;"`
+	PlaylistIDs []int      `json:"playlists" gorm:"-"` // for seeding
+}
+
+type CategoryPlaylist struct {
+	CategoryID string `json:"category_id" gorm:"primaryKey"`
+	PlaylistID int    `json:"playlist_id" gorm:"primaryKey;autoIncrement:false"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Credit roles. Primary and featured artists perform on a song and make up
+// its artist line; producers and writers are only listed in its credits,
+// by name (see SongCreditName).
+const (
+	RolePrimary  = "primary"
+	RoleFeatured = "featured"
+	RoleProducer = "producer"
+	RoleWriter   = "writer"
+)
+
+// PerformerRoles are the roles shown in a track's artists.
+var PerformerRoles = []string{RolePrimary, RoleFeatured}
+
+// SongCredit credits one performing artist on a song. Song.ArtistID stays the billed
+// artist as displayed, which may be a collaboration like "A feat. B";
+// credits name each artist on their own.
+type SongCredit struct {
+	SongID   int    `json:"song_id" gorm:"primaryKey;autoIncrement:false"`
+	ArtistID int    `json:"artist_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Role     string `json:"role" gorm:"primaryKey"`
+	Position int    `json:"position"` // billing order within the role
+	Artist   Artist `json:"-" gorm:"foreignKey:ArtistID"`
+}
+
+// SongCreditName credits a writer or producer on a song. They don't perform,
+// so they are named rather than given an Artist and stay out of the artist
+// catalog, search and related artists.
+type SongCreditName struct {
+	SongID   int    `json:"song_id" gorm:"primaryKey;autoIncrement:false"`
+	Role     string `json:"role" gorm:"primaryKey"`
+	Position int    `json:"position" gorm:"primaryKey;autoIncrement:false"` // billing order within the role
+	Name     string `json:"name"`
+}
+
+// AlbumCredit credits one artist on an album, like SongCredit.
+type AlbumCredit struct {
+	AlbumID  int    `json:"album_id" gorm:"primaryKey;autoIncrement:false"`
+	ArtistID int    `json:"artist_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Role     string `json:"role" gorm:"primaryKey"`
+	Position int    `json:"position"`
+	Artist   Artist `json:"-" gorm:"foreignKey:ArtistID"`
+}
+
+// TrackCredits is an entry of the "credits" section of defaults.json: the
+// people behind a song besides its performers, who come from its billing
+// line.
+type TrackCredits struct {
+	SongID    int      `json:"song_id"`
+	Label     string   `json:"label"`
+	Writers   []string `json:"writers"`
+	Producers []string `json:"producers"`
+}
+
+// ArtistCreditName is one artist named in a billing line.
+type ArtistCreditName struct {
+	Name string
+	Role string
+}
+
+var (
+	featSplit   = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+`)
+	joinerSplit = regexp.MustCompile(`\s*(?:,|&)\s*`)
+)
+
+// SplitArtistCredits breaks a billing line into its artists: everyone
+// before "feat." is primary and everyone after is featured. Band names can
+// contain "&" and "," too, so a side is only split on them when isArtist
+// knows one of the pieces: "Drake feat. Wizkid & Kyla" gives Drake
+// (primary), Wizkid and Kyla (featured) once Wizkid is an artist, while
+// "Earth, Wind & Fire" stays one artist. A nil isArtist never splits on
+// them.
+func SplitArtistCredits(line string, isArtist func(name string) bool) []ArtistCreditName {
+	var out []ArtistCreditName
+	for i, side := range featSplit.Split(strings.TrimSpace(line), 2) {
+		role := RolePrimary
+		if i > 0 {
+			role = RoleFeatured
+		}
+		names := []string{side}
+		if pieces := joinerSplit.Split(side, -1); len(pieces) > 1 && isArtist != nil {
+			for _, p := range pieces {
+				if isArtist(strings.TrimSpace(p)) {
+					names = pieces
+					break
+				}
+			}
+		}
+		for _, name := range names {
+			if name = strings.TrimSpace(name); name != "" {
+				out = append(out, ArtistCreditName{Name: name, Role: role})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArtistCredits(t *testing.T) {
+	known := map[string]bool{"drake": true, "wizkid": true, "post malone": true}
+	isArtist := func(name string) bool { return known[strings.ToLower(name)] }
+
+	tests := []struct {
+		name string
+		line string
+		want []ArtistCreditName
+	}{
+		{
+			name: "one artist",
+			line: "Drake",
+			want: []ArtistCreditName{{"Drake", RolePrimary}},
+		},
+		{
+			name: "featured artists",
+			line: "Post Malone feat. 21 Savage",
+			want: []ArtistCreditName{{"Post Malone", RolePrimary}, {"21 Savage", RoleFeatured}},
+		},
+		{
+			name: "ft in any case",
+			line: "Drake FT. Kyla",
+			want: []ArtistCreditName{{"Drake", RolePrimary}, {"Kyla", RoleFeatured}},
+		},
+		{
+			name: "featuring",
+			line: "Drake featuring Kyla",
+			want: []ArtistCreditName{{"Drake", RolePrimary}, {"Kyla", RoleFeatured}},
+		},
+		{
+			name: "joined artists split when one is known",
+			line: "Drake feat. Wizkid & Kyla",
+			want: []ArtistCreditName{{"Drake", RolePrimary}, {"Wizkid", RoleFeatured}, {"Kyla", RoleFeatured}},
+		},
+		{
+			name: "joined primaries split when one is known",
+			line: "Post Malone & Swae Lee",
+			want: []ArtistCreditName{{"Post Malone", RolePrimary}, {"Swae Lee", RolePrimary}},
+		},
+		{
+			name: "band names stay whole",
+			line: "Earth, Wind & Fire",
+			want: []ArtistCreditName{{"Earth, Wind & Fire", RolePrimary}},
+		},
+		{
+			name: "band as a featured artist",
+			line: "Drake feat. Simon & Garfunkel",
+			want: []ArtistCreditName{{"Drake", RolePrimary}, {"Simon & Garfunkel", RoleFeatured}},
+		},
+		{
+			name: "surrounding space",
+			line: "  Drake feat.  Wizkid ",
+			want: []ArtistCreditName{{"Drake", RolePrimary}, {"Wizkid", RoleFeatured}},
+		},
+		{
+			name: "empty line",
+			line: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitArtistCredits(tt.line, isArtist); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitArtistCredits(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil lookup only splits on feat", func(t *testing.T) {
+		want := []ArtistCreditName{{"Post Malone & Swae Lee", RolePrimary}, {"Wizkid", RoleFeatured}}
+		if got := SplitArtistCredits("Post Malone & Swae Lee ft Wizkid", nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitArtistCredits = %v, want %v", got, want)
+		}
+	})
+}
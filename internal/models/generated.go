@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// SystemUserName owns the generated "Made for you" playlists.
+const SystemUserName = "Spotify"
+
+// PlaylistVersion is a snapshot of a generated playlist, kept each time it
+// is refreshed.
+type PlaylistVersion struct {
+	ID          uint           `json:"-" gorm:"primaryKey"`
+	PlaylistID  int            `json:"playlist_id" gorm:"uniqueIndex:idx_playlist_version"`
+	Version     int            `json:"version" gorm:"uniqueIndex:idx_playlist_version"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	SongIDs     datatypes.JSON `json:"song_ids"` // JSON array of song IDs, in playlist order
+	CreatedAt   time.Time      `json:"created_at"`
+}
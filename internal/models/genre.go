@@ -0,0 +1,36 @@
+package models
+
+import "strings"
+
+// Genre is one entry of the genre catalog. Slug is the lowercase,
+// URL-safe form used as a recommendation seed, e.g. "hip-hop" or "r-n-b".
+type Genre struct {
+	ID    int    `json:"id" gorm:"primaryKey"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug" gorm:"uniqueIndex"`
+	Songs []Song `json:"-" gorm:"many2many:song_genres;"`
+}
+
+// SongGenre is the join between songs and genres.
+type SongGenre struct {
+	SongID  int `json:"song_id" gorm:"primaryKey"`
+	GenreID int `json:"genre_id" gorm:"primaryKey"`
+}
+
+// GenreSlug turns a display name into its catalog slug:
+// "Hip Hop" → "hip-hop", "R&B" → "r-n-b".
+func GenreSlug(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "&", " n ")
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
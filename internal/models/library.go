@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"time"
@@ -13,6 +14,12 @@ type Playlist struct {
 	LastUpdated time.Time `gorm:"autoUpdateTime" json:"last_updated"`
 	UserID      int       `json:"user_id"`
 	Owner       User      `gorm:"foreignKey:UserID"`
+	FolderID    *int      `json:"folder_id"` // nil when the playlist sits at the library root
+	Description string    `json:"description"`
+	MadeForID   *int      `json:"made_for_id"`                           // set on generated playlists: the listener they were made for
+	Generator   string    `json:"generator,omitempty" gorm:"default:''"` // "daily_mix" | "discover_weekly" for generated playlists, "wrapped" for saved Wrapped top songs
+	Version     int       `json:"version"`                               // bumped each time a generated playlist is refreshed
+	Featured    bool      `json:"featured"`                              // editorial pick for GET /browse/featured-playlists
 	Songs       []Song    `gorm:"many2many:playlist_songs;"`
 	SongIDs     []int     `gorm:"-" json:"songs"`
 }
@@ -20,6 +27,7 @@ type Playlist struct {
 type PlaylistSong struct {
 	PlaylistID int `json:"playlist_id"`
 	SongID     int `json:"song_id"`
+	Position   int `json:"position"` // track order; ties (e.g. seeded tracks) keep insertion order
 }
 
 type Song struct {
@@ -35,69 +43,228 @@ type Song struct {
 	AlbumID int   `json:"album_id"`
 	Album   Album `gorm:"foreignKey:AlbumID"` // the actual relation
 
-	Genres   datatypes.JSON `json:"genres"` // ← store raw JSON in SQLite
-	Duration int            `json:"duration"`
-	AudioURL string         `json:"audio_url" default:"/media/song.mp3"`
+	Genres    datatypes.JSON `json:"genres"`                          // seed data from defaults.json only; moved into song_genres on startup and cleared
+	GenreTags []Genre        `json:"-" gorm:"many2many:song_genres;"` // normalized genres, use these for queries
+	Duration  int            `json:"duration"`
+	AudioURL  string         `json:"audio_url" default:"/media/song.mp3"`
+	Label     string         `json:"label"` // record label; empty means the album's
+
+	DiscNumber  int `json:"disc_number" gorm:"default:1"`
+	TrackNumber int `json:"track_number"` // position on the disc; numbered in ID order when 0
 }
 
 type Artist struct {
-	ArtistId int    `json:"artist_id" gorm:"primaryKey"`
-	Name     string `json:"name"`
-	Songs    []Song `gorm:"foreignKey:ArtistID"` // association to songs
-	SongIDs  []int  `gorm:"-" json:"songs"`      // for frontend convenience
-	Image    string `json:"image"`               // optional image for the artist
+	ArtistId    int    `json:"artist_id" gorm:"primaryKey"`
+	Name        string `json:"name"`
+	Songs       []Song `gorm:"foreignKey:ArtistID"` // association to songs
+	SongIDs     []int  `gorm:"-" json:"songs"`      // for frontend convenience
+	Image       string `json:"image"`               // optional image for the artist
+	HeaderImage string `json:"header_image"`        // optional wide banner for the artist page
+	Followers   int    `json:"followers"`           // followers from outside the app; library follows are added on top
 }
 type Album struct {
-	AlbumId  int    `json:"album_id" gorm:"primaryKey"`
-	Title    string `json:"title"`
-	ArtistID int    `json:"artist_id"`           // foreign key column
-	Artist   Artist `gorm:"foreignKey:ArtistID"` // association
-	Cover    string `json:"cover"`
-	Songs    []Song `gorm:"foreignKey:AlbumID"` // association to songs
-	SongIDs  []int  `gorm:"-" json:"songs"`     // for
-	Image    string `json:"image"`              // optional image for the album
+	AlbumId   int    `json:"album_id" gorm:"primaryKey"`
+	Title     string `json:"title"`
+	ArtistID  int    `json:"artist_id"`           // foreign key column
+	Artist    Artist `gorm:"foreignKey:ArtistID"` // association
+	Cover     string `json:"cover"`
+	Songs     []Song `gorm:"foreignKey:AlbumID"` // association to songs
+	SongIDs   []int  `gorm:"-" json:"songs"`     // for
+	Image     string `json:"image"`              // optional image for the album
+	AlbumType string `json:"album_type"`         // "album" | "single" | "compilation"; classified from the tracks when empty
+
+	ReleaseDate          string         `json:"release_date"`           // "2020", "2020-03" or "2020-03-20"
+	ReleaseDatePrecision string         `json:"release_date_precision"` // "year" | "month" | "day"; read off ReleaseDate when empty
+	Label                string         `json:"label"`
+	Copyrights           datatypes.JSON `json:"copyrights"` // [{"text": "© 2020 ...", "type": "C"}, {"text": "℗ 2020 ...", "type": "P"}]
 }
 
+// Copyright is one entry of Album.Copyrights: "C" for the composition, "P"
+// for the sound recording.
+type Copyright struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// releaseDateLayouts maps each release date precision to its layout.
+var releaseDateLayouts = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"day":   "2006-01-02",
+}
+
+// ReleaseDatePrecisionOf tells how precise a release date is, or "" if
+// it isn't one.
+func ReleaseDatePrecisionOf(date string) string {
+	for precision, layout := range releaseDateLayouts {
+		if len(date) == len(layout) {
+			if _, err := time.Parse(layout, date); err == nil {
+				return precision
+			}
+		}
+	}
+	return ""
+}
+
+// DatePrecision is the album's release date precision, read off its
+// release date unless set.
+func (a Album) DatePrecision() string {
+	if a.ReleaseDatePrecision != "" {
+		return a.ReleaseDatePrecision
+	}
+	return ReleaseDatePrecisionOf(a.ReleaseDate)
+}
+
+// CopyrightList decodes Copyrights, skipping it if malformed.
+func (a Album) CopyrightList() []Copyright {
+	list := []Copyright{}
+	if len(a.Copyrights) > 0 {
+		if err := json.Unmarshal(a.Copyrights, &list); err != nil {
+			return []Copyright{}
+		}
+	}
+	return list
+}
+
+// AlbumTypeFor classifies a release the way Spotify does: one to six
+// tracks running under 30 minutes is a single (Spotify files EPs of four to
+// six tracks as singles too). The catalog often holds only some tracks of
+// an album, so a release also needs a title track, one named like the
+// release, to count as a single.
+func AlbumTypeFor(tracks, durationSec int, titleTrack bool) string {
+	if titleTrack && tracks > 0 && tracks <= 6 && durationSec < 30*60 {
+		return "single"
+	}
+	return "album"
+}
+
+// PodcastEpisode is one episode of a show. IDs are unique across shows.
 type PodcastEpisode struct {
-	ID          int
-	Title       string `json:"title"`
-	Duration    int    `json:"duration"`
-	AudioURL    string `json:"audio_url"`
-	Description string `json:"description"`
+	ID          int       `json:"id" gorm:"primaryKey"`
+	PodcastID   int       `json:"podcast_id" gorm:"index"`
+	Number      int       `json:"number"` // position within the show; the ID episodes had before they got their own table
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Duration    int       `json:"duration"` // in seconds
+	AudioURL    string    `json:"audio_url"`
+	ReleaseDate time.Time `json:"release_date" gorm:"index"`
+	Explicit    bool      `json:"explicit"`
+	GUID        string    `json:"guid" gorm:"index"` // the feed item's guid, for imported episodes
+	Image       string    `json:"image"`             // episode artwork; empty means the show's cover
+}
+
+// EpisodeProgress is a user's resume point in one episode.
+type EpisodeProgress struct {
+	UserID      int       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	EpisodeID   int       `json:"episode_id" gorm:"primaryKey;autoIncrement:false"`
+	PositionMs  int       `json:"position_ms"`
+	FullyPlayed bool      `json:"fully_played"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LegacyEpisode is an entry of a podcast's Episodes JSON, as in
+// defaults.json; its ID is only unique within the show.
+type LegacyEpisode struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Duration    int        `json:"duration"`
+	AudioURL    string     `json:"audio_url"`
+	ReleaseDate *time.Time `json:"release_date"`
+	Explicit    bool       `json:"explicit"`
 }
 
 type Podcast struct {
-	ID       int            `json:"id" gorm:"primaryKey"`
-	Title    string         `json:"title"`
-	Hosts    datatypes.JSON `json:"hosts" gorm:"type:json"`
-	Cover    string         `json:"cover"`
+	ID    int            `json:"id" gorm:"primaryKey"`
+	Title string         `json:"title"`
+	Hosts datatypes.JSON `json:"hosts" gorm:"type:json"`
+	Cover string         `json:"cover"`
+
+	Description string `json:"description"`
+	Publisher   string `json:"publisher"`
+	Language    string `json:"language"` // e.g. "en-us"
+	Explicit    bool   `json:"explicit"`
+	FeedURL     string `json:"feed_url" gorm:"index"` // set on shows imported from RSS; re-imports match on it
+
+	// Episodes only carries seed data from defaults.json; it is moved into
+	// the podcast_episodes table on startup and cleared.
 	Episodes datatypes.JSON `json:"episodes" gorm:"type:json"`
 }
 
+// LibraryItem is one row of the unified library list; Type tells the
+// client which kind of object ID points to.
+type LibraryItem struct {
+	Type         string     `json:"type"` // "playlist" | "album" | "podcast" | "artist" | "folder"
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Subtitle     string     `json:"subtitle"`
+	Cover        string     `json:"cover"`
+	Creator      string     `json:"creator"`
+	Pinned       bool       `json:"pinned"`
+	PinnedAt     *time.Time `json:"pinned_at,omitempty"`
+	Downloaded   bool       `json:"downloaded"`
+	AddedAt      time.Time  `json:"added_at"`
+	LastPlayedAt *time.Time `json:"last_played_at,omitempty"`
+
+	FolderID *int          `json:"folder_id,omitempty"` // parent folder, if any
+	Children []LibraryItem `json:"children,omitempty"`  // only set on folders
+}
+
+// Folder groups playlists (and other folders) in a user's library.
+type Folder struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	ParentID  *int      `json:"parent_id"` // nil for top-level folders
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // Bundle into a single response object
 type LibraryData struct {
-	Playlists []Playlist `json:"playlists"`
-	Albums    []Album    `json:"albums"`
-	Podcasts  []Podcast  `json:"podcasts"`
+	Filter string        `json:"filter"`
+	Sort   string        `json:"sort"`
+	Items  []LibraryItem `json:"items"`
 }
 
 type LibraryEntry struct {
 	gorm.Model
 	ID          int    `gorm:"primaryKey"`
-	Type        string // "playlist" | "album" | "podcast"
+	UserID      int    `json:"user_id"`
+	Type        string // "playlist" | "album" | "podcast" | "artist" | "folder"
 	ReferenceID string // points to the real Playlist.ID, Album.ID, etc.
 	Title       string
 	Subtitle    string
 	IconURL     string
+	Pinned      bool       `json:"pinned"`
+	PinnedAt    *time.Time `json:"pinned_at"`
+}
+
+// Download marks an item as saved for offline use on one of a user's devices.
+type Download struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	UserID      int       `gorm:"uniqueIndex:idx_download" json:"user_id"`
+	DeviceID    string    `gorm:"uniqueIndex:idx_download" json:"device_id"`
+	Type        string    `gorm:"uniqueIndex:idx_download" json:"type"` // "track" | "album" | "playlist" | "episode"
+	ReferenceID int       `gorm:"uniqueIndex:idx_download" json:"reference_id"`
+	ParentID    int       `gorm:"uniqueIndex:idx_download" json:"parent_id"` // podcast ID for episodes, else 0
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type RecentPlay struct {
 	ID          uint      `gorm:"primaryKey" json:"-"`
 	UserID      int       `json:"user_id"`
-	Type        string    `json:"type"`         // "track", "artist", "album", "playlist", "podcast"
-	ReferenceID int       `json:"reference_id"` // the ID of the item played
-	OriginID    int       `json:"origin_id"`
-	PlayedAt    time.Time `gorm:"autoCreateTime" json:"played_at"`
+	Type        string    `json:"type"`                                  // "track", "artist", "album", "playlist", "podcast"
+	ItemType    string    `gorm:"default:track" json:"item_type"`        // "track" | "episode"
+	ReferenceID int       `json:"reference_id"`                          // the ID of the item played
+	OriginID    int       `json:"origin_id"`                             // the context (playlist, album…) it was played from
+	PlayedAt    time.Time `gorm:"autoCreateTime;index" json:"played_at"` // when playback ended
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	MsPlayed    int        `json:"ms_played"`
+	ReasonStart string     `json:"reason_start,omitempty"` // e.g. "clickrow", "trackdone", "fwdbtn"
+	ReasonEnd   string     `json:"reason_end,omitempty"`   // e.g. "trackdone", "endplay", "fwdbtn"
+	Skipped     bool       `json:"skipped"`
 }
 
 type Newsletter struct {
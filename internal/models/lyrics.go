@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lyrics are a song's lyrics as seeded from defaults.json. An LRC file next
+// to the song's audio takes precedence over them.
+type Lyrics struct {
+	SongID   int    `json:"song_id" gorm:"primaryKey;autoIncrement:false"`
+	Language string `json:"language"` // ISO 639-1, e.g. "en"
+	LRC      string `json:"lrc"`      // line-synced, in LRC format
+	Text     string `json:"text"`     // plain lyrics, used when LRC is empty
+}
+
+// LyricLine is one line of lyrics and when it starts.
+type LyricLine struct {
+	StartMs int    `json:"start_ms"`
+	Text    string `json:"text"`
+}
+
+var (
+	lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	lrcTag       = regexp.MustCompile(`(?i)^\[([a-z#]+):(.*)\]$`)
+	lrcWordStamp = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// ParseLRC reads line-synced lyrics in LRC format. A line may carry several
+// timestamps ("[00:12.00][01:30.50]chorus"), [offset:+/-ms] shifts every
+// line, and other tags like [ar:] are ignored, in any case. Word timestamps
+// of enhanced LRC ("<00:12.50>word") are dropped and lines without a
+// timestamp are skipped. Lines come back in time order.
+func ParseLRC(lrc string) ([]LyricLine, error) {
+	var lines []LyricLine
+	offset := 0
+	for n, raw := range strings.Split(strings.ReplaceAll(lrc, "\r\n", "\n"), "\n") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if m := lrcTag.FindStringSubmatch(raw); m != nil && !lrcTimestamp.MatchString(raw) {
+			if strings.EqualFold(m[1], "offset") {
+				v, err := strconv.Atoi(strings.TrimSpace(m[2]))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid offset %q", n+1, m[2])
+				}
+				offset = v
+			}
+			continue
+		}
+
+		var starts []int
+		for {
+			m := lrcTimestamp.FindStringSubmatch(raw)
+			if m == nil {
+				break
+			}
+			mins, _ := strconv.Atoi(m[1])
+			secs, _ := strconv.Atoi(m[2])
+			ms := 0
+			if m[3] != "" {
+				// ".5" is 500ms, ".50" is 500ms, ".500" is 500ms
+				frac := (m[3] + "00")[:3]
+				ms, _ = strconv.Atoi(frac)
+			}
+			starts = append(starts, (mins*60+secs)*1000+ms)
+			raw = raw[len(m[0]):]
+		}
+		if len(starts) == 0 {
+			continue
+		}
+		text := strings.Join(strings.Fields(lrcWordStamp.ReplaceAllString(raw, "")), " ")
+		for _, start := range starts {
+			lines = append(lines, LyricLine{StartMs: start, Text: text})
+		}
+	}
+
+	for i := range lines {
+		// a positive offset makes lyrics show up sooner
+		if lines[i].StartMs -= offset; lines[i].StartMs < 0 {
+			lines[i].StartMs = 0
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].StartMs < lines[j].StartMs })
+	return lines, nil
+}
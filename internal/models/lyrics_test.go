@@ -0,0 +1,90 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLRC(t *testing.T) {
+	tests := []struct {
+		name string
+		lrc  string
+		want []LyricLine
+	}{
+		{
+			name: "lines in time order",
+			lrc:  "[00:05.00]second\n[00:01.00]first",
+			want: []LyricLine{{1000, "first"}, {5000, "second"}},
+		},
+		{
+			name: "several timestamps on a line",
+			lrc:  "[00:01.00]verse\n[00:02.00][00:10.00]chorus",
+			want: []LyricLine{{1000, "verse"}, {2000, "chorus"}, {10000, "chorus"}},
+		},
+		{
+			name: "fraction padding",
+			lrc:  "[00:01.5]a\n[00:02.50]b\n[00:03.500]c\n[00:04:25]d\n[00:05]e",
+			want: []LyricLine{{1500, "a"}, {2500, "b"}, {3500, "c"}, {4250, "d"}, {5000, "e"}},
+		},
+		{
+			name: "positive offset shows lyrics sooner",
+			lrc:  "[offset:+500]\n[00:01.00]a\n[00:00.20]b",
+			want: []LyricLine{{0, "b"}, {500, "a"}},
+		},
+		{
+			name: "negative offset shows lyrics later",
+			lrc:  "[offset:-250]\n[00:01.00]a",
+			want: []LyricLine{{1250, "a"}},
+		},
+		{
+			name: "tags in any case",
+			lrc:  "[ar:Artist]\n[TI:Title]\n[Offset:100]\n[00:01.00]a",
+			want: []LyricLine{{900, "a"}},
+		},
+		{
+			name: "enhanced word timestamps",
+			lrc:  "[00:01.00]<00:01.00>Hello <00:01.50>there, <00:02.00>world",
+			want: []LyricLine{{1000, "Hello there, world"}},
+		},
+		{
+			name: "untimed lines are skipped",
+			lrc:  "Intro\r\n[00:01.00]a\r\n\r\nno stamp here\r\n[00:02.00]b",
+			want: []LyricLine{{1000, "a"}, {2000, "b"}},
+		},
+		{
+			name: "instrumental gap",
+			lrc:  "[00:01.00]a\n[00:02.00]",
+			want: []LyricLine{{1000, "a"}, {2000, ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLRC(tt.lrc)
+			if err != nil {
+				t.Fatalf("ParseLRC: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLRC = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLRCMalformed(t *testing.T) {
+	for _, lrc := range []string{
+		"[offset:soon]\n[00:01.00]a",
+		"[offset:]\n[00:01.00]a",
+	} {
+		if _, err := ParseLRC(lrc); err == nil {
+			t.Errorf("ParseLRC(%q) succeeded, want an error", lrc)
+		}
+	}
+
+	got, err := ParseLRC("just plain text\nwith no timestamps")
+	if err != nil {
+		t.Fatalf("ParseLRC: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ParseLRC of untimed text = %v, want no lines", got)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RadioStation is an endless stream of recommendations grown from one seed.
+type RadioStation struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id"`
+	SeedType  string    `json:"seed_type"` // "track" | "artist" | "album" | "playlist"
+	SeedID    int       `json:"seed_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RadioTrack is one song a station has served, with the listener's
+// feedback on it.
+type RadioTrack struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	StationID int       `json:"station_id" gorm:"index"`
+	SongID    int       `json:"song_id"`
+	Batch     int       `json:"batch"`
+	Feedback  string    `json:"feedback"` // "" | "like" | "skip"
+	ServedAt  time.Time `json:"served_at" gorm:"autoCreateTime"`
+}
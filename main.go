@@ -16,13 +16,15 @@ import (
 	"spotify-mock-api/internal/handlers"
 	"spotify-mock-api/internal/models"
 	"spotify-mock-api/internal/utils"
+	"strings"
+	"time"
 )
 
 var db *gorm.DB
 
 func main() {
 	// Initialize SQLite database
-	db, err := gorm.Open(sqlite.Open("app.db"), &gorm.Config{Logger: logger.Default.LogMode(logger.Info)})
+	db, err := gorm.Open(sqlite.Open("app.db?_pragma=busy_timeout(5000)"), &gorm.Config{Logger: logger.Default.LogMode(logger.Info)})
 	if err != nil {
 		log.Fatal("failed to connect database:", err)
 	}
@@ -33,10 +35,27 @@ func main() {
 		&models.Album{},
 		&models.Song{},
 		&models.Playlist{},
+		&models.PlaylistSong{},
 		&models.Podcast{},
 		&models.LibraryEntry{},
+		&models.Folder{},
 		&models.User{},
 		&models.RecentPlay{},
+		&models.Download{},
+		&models.AudioFeatures{},
+		&models.AudioAnalysis{},
+		&models.Genre{},
+		&models.SongGenre{},
+		&models.SongCredit{},
+		&models.SongCreditName{},
+		&models.AlbumCredit{},
+		&models.RadioStation{},
+		&models.RadioTrack{},
+		&models.PlaylistVersion{},
+		&models.Category{},
+		&models.Lyrics{},
+		&models.PodcastEpisode{},
+		&models.EpisodeProgress{},
 		&models.Newsletter{},
 	); err != nil {
 		log.Fatal("migration failed:", err)
@@ -47,13 +66,40 @@ func main() {
 		log.Fatal("seeding defaults failed:", err)
 	}
 
+	// `go run main.go import-feed <url or file>...` imports podcast feeds and exits
+	if len(os.Args) > 1 && os.Args[1] == "import-feed" {
+		if len(os.Args) < 3 {
+			log.Fatal("usage: import-feed <feed URL or file>...")
+		}
+		for _, source := range os.Args[2:] {
+			res, err := handlers.ImportPodcastFeed(db, source)
+			if err != nil {
+				log.Fatalf("import %s: %v", source, err)
+			}
+			log.Printf("imported %q as podcast %d: %d new episodes, %d updated",
+				res.Title, res.PodcastID, res.EpisodesCreated, res.EpisodesUpdated)
+		}
+		return
+	}
+
+	// 4) Keep the generated "Made for you" playlists fresh
+	interval := 24 * time.Hour
+	if v := os.Getenv("PLAYLIST_REFRESH_INTERVAL"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil {
+			log.Fatal("invalid PLAYLIST_REFRESH_INTERVAL:", err)
+		}
+	}
+	if interval > 0 {
+		handlers.StartPlaylistGenerator(db, interval)
+	}
+
 	r := gin.Default()
 
 	// CORS wrapper
 	handler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:19006", "http://localhost:8081"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Device-ID"},
 		AllowCredentials: true,
 	}).Handler(r)
 
@@ -65,15 +111,53 @@ func main() {
 	// Track endpoints
 	r.GET("/tracks/:id", trackH.GetTrackByID)
 	r.GET("/tracks/:id/audio", handlers.GetTrackAudio)
+	r.GET("/tracks/:id/credits", handlers.GetTrackCredits(db))
+	r.GET("/tracks/:id/lyrics", handlers.GetTrackLyrics(db))
 	r.GET("/tracks/recent", handlers.GetRecentTracks(db))
+	r.GET("/audio-features", handlers.GetSeveralAudioFeatures(db))
+	r.GET("/audio-features/:id", handlers.GetAudioFeatures(db))
+	r.GET("/audio-analysis/:id", handlers.GetAudioAnalysis(db))
 
 	//Playlist
 	r.GET("/library", handlers.GetLibraryData(db))
+	r.PUT("/library/:type/:id/pin", handlers.PinLibraryItem(db))
+	r.DELETE("/library/:type/:id/pin", handlers.UnpinLibraryItem(db))
+	r.GET("/library/folders", handlers.ListFolders(db))
+	r.POST("/library/folders", handlers.CreateFolder(db))
+	r.GET("/library/folders/:id", handlers.GetFolder(db))
+	r.PUT("/library/folders/:id", handlers.UpdateFolder(db))
+	r.DELETE("/library/folders/:id", handlers.DeleteFolder(db))
 
 	r.GET("/me", handlers.GetCurrentUser(db))
 	r.GET("/me/:id/recent", handlers.GetRecentPlays(db))
-
+	r.GET("/me/player/recently-played", handlers.GetRecentlyPlayed(db))
+	r.POST("/me/plays", handlers.ReportPlay(db))
+	r.GET("/me/top/artists", handlers.GetTopArtists(db))
+	r.GET("/me/top/tracks", handlers.GetTopTracks(db))
+	r.GET("/me/top/genres", handlers.GetTopGenres(db))
+	r.GET("/me/wrapped/:year", handlers.GetWrapped(db))
+	r.POST("/me/wrapped/:year/playlist", handlers.SaveWrappedPlaylist(db))
+
+	r.GET("/home", handlers.GetHome(db))
+	r.GET("/me/made-for-you", handlers.GetMadeForYou(db))
+	r.POST("/me/made-for-you/refresh", handlers.RefreshMadeForYou(db))
 	r.GET("/me/recommendations", handlers.GetRecommendations(db))
+	r.GET("/recommendations", handlers.GetSeedRecommendations(db))
+	r.GET("/recommendations/available-genre-seeds", handlers.GetAvailableGenreSeeds(db))
+	r.GET("/browse/new-releases", handlers.GetNewReleases(db))
+	r.GET("/browse/featured-playlists", handlers.GetFeaturedPlaylists(db))
+	r.GET("/browse/categories", handlers.GetCategories(db))
+	r.GET("/browse/categories/:id/playlists", handlers.GetCategoryPlaylists(db))
+	r.GET("/genres", handlers.ListGenres(db))
+	r.GET("/genres/:name/tracks", handlers.GetGenreTracks(db))
+
+	r.POST("/radio", handlers.CreateRadio(db))
+	r.GET("/radio/:id/next", handlers.GetRadioNext(db))
+	r.POST("/radio/:id/feedback", handlers.SetRadioFeedback(db))
+
+	r.GET("/me/downloads", handlers.GetDownloads(db))
+	r.PUT("/me/downloads", handlers.SaveDownloads(db))
+	r.DELETE("/me/downloads", handlers.RemoveDownloads(db))
 
 	// Search endpoint
 	r.GET("/search", handlers.GetSearch(db))
@@ -82,7 +166,19 @@ func main() {
 	r.POST("/playlists", handlers.CreatePlaylist(db))
 	r.GET("/albums/:id", handlers.GetAlbumDetail(db))
 	r.GET("/artists/:id", handlers.GetArtistDetail(db))
+	r.GET("/artists/:id/related-artists", handlers.GetRelatedArtists(db))
+	r.GET("/artists/:id/top-tracks", handlers.GetArtistTopTracks(db))
+	r.GET("/artists/:id/albums", handlers.GetArtistAlbums(db))
 	r.GET("/podcasts/:id", handlers.GetPodcastDetail(db))
+	r.GET("/shows/:id/episodes", handlers.GetShowEpisodes(db))
+	r.GET("/shows/:id/feed.xml", handlers.GetShowFeed(db))
+	r.GET("/episodes/:id", handlers.GetEpisode(db))
+	r.PUT("/me/episodes/:id/resume-point", handlers.SaveResumePoint(db))
+
+	// admin endpoints are off unless ENABLE_ADMIN=1
+	if os.Getenv("ENABLE_ADMIN") == "1" {
+		r.POST("/admin/podcasts/import", handlers.ImportPodcast(db))
+	}
 
 	// newsletters
 	r.GET("/newsletters", handlers.GetNewsletters(db))
@@ -91,6 +187,9 @@ func main() {
 	r.DELETE("/playlists/:id/tracks/:trackId", handlers.RemoveTrackFromPlaylist(db))
 	r.PUT("/playlists/:id", handlers.UpdatePlaylistMeta(db))
 	r.PUT("/playlists/:id/reorder", handlers.ReorderPlaylist(db))
+	r.PUT("/playlists/:id/folder", handlers.MovePlaylistToFolder(db))
+	r.GET("/playlists/:id/versions", handlers.GetPlaylistVersions(db))
+	r.GET("/playlists/:id/versions/:version", handlers.GetPlaylistVersion(db))
 
 	// Start server
 	localIP := utils.GetLocalIP()
@@ -103,14 +202,18 @@ func main() {
 
 // define a struct matching defaults.json
 type Defaults struct {
-	Songs          []models.Song         `json:"songs"`
-	Albums         []models.Album        `json:"albums"`
-	Artists        []models.Artist       `json:"artists"`
-	Playlists      []models.Playlist     `json:"playlists"`
-	Podcasts       []models.Podcast      `json:"podcasts"`
-	LibraryEntries []models.LibraryEntry `json:"libraryEntries"`
-	Users          []models.User         `json:"users"`
-	Newsletters    []models.Newsletter   `json:"newsletter"`
+	Songs          []models.Song          `json:"songs"`
+	Albums         []models.Album         `json:"albums"`
+	Artists        []models.Artist        `json:"artists"`
+	Playlists      []models.Playlist      `json:"playlists"`
+	Podcasts       []models.Podcast       `json:"podcasts"`
+	LibraryEntries []models.LibraryEntry  `json:"libraryEntries"`
+	Users          []models.User          `json:"users"`
+	Newsletters    []models.Newsletter    `json:"newsletter"`
+	AudioFeatures  []models.AudioFeatures `json:"audioFeatures"`
+	Credits        []models.TrackCredits  `json:"credits"`
+	Categories     []models.Category      `json:"categories"`
+	Lyrics         []models.Lyrics        `json:"lyrics"`
 }
 
 func seedDefaults(db *gorm.DB) error {
@@ -154,12 +257,106 @@ func seedDefaults(db *gorm.DB) error {
 		log.Printf("seeded %d songs", len(defs.Songs))
 	}
 
-	// Seed Playlists
+	// Move Song.Genres JSON into the genre catalog
+	if err := migrateSongGenres(db); err != nil {
+		return fmt.Errorf("migrate song genres: %w", err)
+	}
+
+	// Credit every artist named in a song's or album's billing line
+	if err := migrateArtistCredits(db); err != nil {
+		return fmt.Errorf("migrate artist credits: %w", err)
+	}
+
+	// Number album tracks that have no track number yet
+	if err := numberAlbumTracks(db); err != nil {
+		return fmt.Errorf("number album tracks: %w", err)
+	}
+
+	// Writers and producers are credited by name, not as catalog artists
+	if err := migrateCreditNames(db, defs.Artists); err != nil {
+		return fmt.Errorf("migrate credit names: %w", err)
+	}
+
+	// Writers, producers and labels from the credits section
+	if err := seedTrackCredits(db, defs.Credits); err != nil {
+		return fmt.Errorf("seed track credits: %w", err)
+	}
+
+	// Classify albums that don't say whether they're an album or a single
+	if err := classifyAlbums(db, defs.Albums); err != nil {
+		return fmt.Errorf("classify albums: %w", err)
+	}
+
+	// Seed Audio Features: imported ones first, then generate the rest
+	var afCount int64
+	db.Model(&models.AudioFeatures{}).Count(&afCount)
+	if afCount == 0 && len(defs.AudioFeatures) > 0 {
+		if err := db.Create(&defs.AudioFeatures).Error; err != nil {
+			return fmt.Errorf("insert audio features: %w", err)
+		}
+		log.Printf("seeded %d audio features", len(defs.AudioFeatures))
+	}
+	var featureless []models.Song
+	if err := db.
+		Preload("GenreTags").
+		Where("id NOT IN (?)", db.Model(&models.AudioFeatures{}).Select("song_id")).
+		Find(&featureless).
+		Error; err != nil {
+		return fmt.Errorf("find songs without audio features: %w", err)
+	}
+	if len(featureless) > 0 {
+		generated := make([]models.AudioFeatures, len(featureless))
+		for i, s := range featureless {
+			generated[i] = models.GenerateAudioFeatures(s)
+		}
+		if err := db.CreateInBatches(&generated, 100).Error; err != nil {
+			return fmt.Errorf("insert generated audio features: %w", err)
+		}
+		log.Printf("generated audio features for %d songs", len(generated))
+	}
+
+	// Seed User
+	var userCount int64
+	db.Model(&models.User{}).Count(&userCount)
+	if userCount == 0 {
+		if _, err := os.Stat("media/avatar.jpg"); err != nil {
+			log.Println("warning: avatar.jpg not found; using placeholder")
+		}
+		if err := db.Create(&defs.Users).Error; err != nil {
+			return fmt.Errorf("insert users: %w", err)
+		}
+		log.Println("seeded default user profile")
+	}
+	// Repair databases where the system user was created before the
+	// default users and took user 1's ID
+	if err := repairSystemUser(db, defs); err != nil {
+		return fmt.Errorf("repair system user: %w", err)
+	}
+
+	// Seed Playlists; those without a user_id are editorial, owned by Spotify
+	editor := models.User{Name: models.SystemUserName}
+	if err := db.Where(&editor).Attrs(models.User{Image: "/media/playlist-art.jpg"}).FirstOrCreate(&editor).Error; err != nil {
+		return fmt.Errorf("find system user: %w", err)
+	}
 	for _, p := range defs.Playlists {
-		// 1) create the playlist record (without songs)
-		if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		editorial := p.UserID == 0
+		if editorial {
+			p.UserID = editor.ID
+		}
+		// 1) create the playlist record (without songs); editorial playlists
+		// take their description and featured flag from defaults.json on
+		// every start, while listeners' playlists keep their own edits
+		onConflict := clause.OnConflict{DoNothing: true}
+		if editorial {
+			onConflict = clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"description", "featured"}),
+			}
+		}
+		if err := db.Clauses(onConflict).
 			Create(&models.Playlist{
 				ID: p.ID, Title: p.Title, Cover: p.Cover, UserID: p.UserID,
+				Description: p.Description, Featured: p.Featured,
 			}).Error; err != nil {
 			log.Fatal(err)
 		}
@@ -176,6 +373,33 @@ func seedDefaults(db *gorm.DB) error {
 			}
 		}
 	}
+	// Playlists from before generated playlists have no generator at all
+	if err := db.Exec("UPDATE playlists SET generator = '' WHERE generator IS NULL").Error; err != nil {
+		return fmt.Errorf("backfill playlist generators: %w", err)
+	}
+
+	// Seed Browse categories and the playlists filed under them
+	for _, cat := range defs.Categories {
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Category{
+				ID: cat.ID, Name: cat.Name, Icon: cat.Icon, Color: cat.Color, Position: cat.Position,
+			}).Error; err != nil {
+			return fmt.Errorf("insert category %s: %w", cat.ID, err)
+		}
+		for _, pid := range cat.PlaylistIDs {
+			if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.CategoryPlaylist{CategoryID: cat.ID, PlaylistID: pid}).Error; err != nil {
+				return fmt.Errorf("insert category %s playlist %d: %w", cat.ID, pid, err)
+			}
+		}
+	}
+
+	// Seed Lyrics; songs that already have lyrics keep them
+	if len(defs.Lyrics) > 0 {
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defs.Lyrics).Error; err != nil {
+			return fmt.Errorf("insert lyrics: %w", err)
+		}
+	}
 
 	// Seed Podcasts
 	var pdCount int64
@@ -187,6 +411,11 @@ func seedDefaults(db *gorm.DB) error {
 		log.Printf("seeded %d podcasts", len(defs.Podcasts))
 	}
 
+	// Move episodes out of each podcast's Episodes JSON into their own table
+	if err := migratePodcastEpisodes(db); err != nil {
+		return fmt.Errorf("migrate podcast episodes: %w", err)
+	}
+
 	// Seed LibraryEntries
 	var libCount int64
 	db.Model(&models.LibraryEntry{}).Count(&libCount)
@@ -196,18 +425,9 @@ func seedDefaults(db *gorm.DB) error {
 		}
 		log.Printf("seeded %d library entries", len(defs.LibraryEntries))
 	}
-
-	// Seed User
-	var userCount int64
-	db.Model(&models.User{}).Count(&userCount)
-	if userCount == 0 {
-		if _, err := os.Stat("media/avatar.jpg"); err != nil {
-			log.Println("warning: avatar.jpg not found; using placeholder")
-		}
-		if err := db.Create(&defs.Users).Error; err != nil {
-			return fmt.Errorf("insert users: %w", err)
-		}
-		log.Println("seeded default user profile")
+	// Entries saved before library entries had an owner belong to user 1
+	if err := db.Exec("UPDATE library_entries SET user_id = 1 WHERE user_id = 0").Error; err != nil {
+		return fmt.Errorf("backfill library entry owners: %w", err)
 	}
 
 	// Seed Newsletters
@@ -222,3 +442,460 @@ func seedDefaults(db *gorm.DB) error {
 
 	return nil
 }
+
+// migrateSongGenres moves the genres listed in each song's Genres JSON
+// into the genres table and the song_genres join, then clears the JSON so
+// song_genres is the only record of a song's genres. Names are matched by
+// slug, so "Hip Hop" and "hip-hop" end up as one genre.
+func migrateSongGenres(db *gorm.DB) error {
+	var songs []models.Song
+	if err := db.
+		Where("genres IS NOT NULL AND genres NOT IN ('', 'null')").
+		Find(&songs).
+		Error; err != nil {
+		return err
+	}
+
+	genreIDs := make(map[string]int)
+	var existing []models.Genre
+	if err := db.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, g := range existing {
+		genreIDs[g.Slug] = g.ID
+	}
+
+	links := 0
+	for _, s := range songs {
+		var names []string
+		if len(s.Genres) > 0 {
+			if err := json.Unmarshal(s.Genres, &names); err != nil {
+				log.Printf("warning: song %d has invalid genres %s", s.ID, s.Genres)
+				continue
+			}
+		}
+		for _, name := range names {
+			slug := models.GenreSlug(name)
+			if slug == "" {
+				continue
+			}
+			id, ok := genreIDs[slug]
+			if !ok {
+				g := models.Genre{Name: strings.TrimSpace(name), Slug: slug}
+				if err := db.Create(&g).Error; err != nil {
+					return err
+				}
+				id = g.ID
+				genreIDs[slug] = id
+			}
+			if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.SongGenre{SongID: s.ID, GenreID: id}).
+				Error; err != nil {
+				return err
+			}
+			links++
+		}
+		if err := db.Model(&s).Update("genres", nil).Error; err != nil {
+			return err
+		}
+	}
+	if links > 0 {
+		log.Printf("linked %d song genres (%d genres in catalog)", links, len(genreIDs))
+	}
+	return nil
+}
+
+// numberAlbumTracks gives songs without a track number the next free
+// numbers on their album's disc, in ID order.
+func numberAlbumTracks(db *gorm.DB) error {
+	var songs []models.Song
+	if err := db.
+		Where("album_id IN (?)", db.Model(&models.Song{}).Select("album_id").Where("track_number = 0 OR track_number IS NULL")).
+		Order("album_id, id").
+		Find(&songs).
+		Error; err != nil {
+		return err
+	}
+	type disc struct{ album, number int }
+	last := make(map[disc]int)
+	for _, s := range songs {
+		d := disc{s.AlbumID, s.DiscNumber}
+		if s.TrackNumber > last[d] {
+			last[d] = s.TrackNumber
+		}
+	}
+	numbered := 0
+	for _, s := range songs {
+		if s.TrackNumber > 0 {
+			continue
+		}
+		d := disc{s.AlbumID, s.DiscNumber}
+		last[d]++
+		if err := db.Model(&s).Update("track_number", last[d]).Error; err != nil {
+			return err
+		}
+		numbered++
+	}
+	if numbered > 0 {
+		log.Printf("numbered %d album tracks", numbered)
+	}
+	return nil
+}
+
+// classifyAlbums fills in album_type from the track count, running time and
+// title track, for albums that have none and for seeded albums that don't
+// set one, so they follow the current rules.
+func classifyAlbums(db *gorm.DB, seeded []models.Album) error {
+	unset := []int{0}
+	for _, a := range seeded {
+		if a.AlbumType == "" {
+			unset = append(unset, a.AlbumId)
+		}
+	}
+	var rows []struct {
+		AlbumID    int
+		Tracks     int
+		Duration   int
+		TitleTrack bool
+	}
+	if err := db.
+		Table("albums").
+		Select("albums.album_id, COUNT(songs.id) AS tracks, COALESCE(SUM(songs.duration), 0) AS duration, "+
+			"COALESCE(MAX(LOWER(songs.title) = LOWER(albums.title)), 0) AS title_track").
+		Joins("LEFT JOIN songs ON songs.album_id = albums.album_id").
+		Where("albums.album_type IS NULL OR albums.album_type = '' OR albums.album_id IN ?", unset).
+		Group("albums.album_id").
+		Scan(&rows).
+		Error; err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := db.
+			Model(&models.Album{}).
+			Where("album_id = ?", r.AlbumID).
+			Update("album_type", models.AlbumTypeFor(r.Tracks, r.Duration, r.TitleTrack)).
+			Error; err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		log.Printf("classified %d albums", len(rows))
+	}
+	return nil
+}
+
+// migrateArtistCredits gives songs and albums without credits one credit per
+// artist in their billing line, so "Post Malone feat. 21 Savage" credits
+// Post Malone and 21 Savage. Artists not in the catalog yet are created.
+func migrateArtistCredits(db *gorm.DB) error {
+	artistID, isArtist, err := artistResolver(db)
+	if err != nil {
+		return err
+	}
+	// credits resolves a billing line into credit rows via add
+	credits := func(line string, add func(artistID int, role string, position int) error) error {
+		positions := make(map[string]int)
+		for _, c := range models.SplitArtistCredits(line, isArtist) {
+			id, err := artistID(c.Name)
+			if err != nil {
+				return err
+			}
+			if err := add(id, c.Role, positions[c.Role]); err != nil {
+				return err
+			}
+			positions[c.Role]++
+		}
+		return nil
+	}
+
+	var songs []models.Song
+	if err := db.
+		Preload("Artist").
+		Where("id NOT IN (?)", db.Model(&models.SongCredit{}).Select("song_id")).
+		Find(&songs).
+		Error; err != nil {
+		return err
+	}
+	for _, s := range songs {
+		if err := credits(s.Artist.Name, func(id int, role string, pos int) error {
+			return db.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.SongCredit{SongID: s.ID, ArtistID: id, Role: role, Position: pos}).
+				Error
+		}); err != nil {
+			return err
+		}
+	}
+
+	var albums []models.Album
+	if err := db.
+		Preload("Artist").
+		Where("album_id NOT IN (?)", db.Model(&models.AlbumCredit{}).Select("album_id")).
+		Find(&albums).
+		Error; err != nil {
+		return err
+	}
+	for _, a := range albums {
+		if err := credits(a.Artist.Name, func(id int, role string, pos int) error {
+			return db.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.AlbumCredit{AlbumID: a.AlbumId, ArtistID: id, Role: role, Position: pos}).
+				Error
+		}); err != nil {
+			return err
+		}
+	}
+	if len(songs)+len(albums) > 0 {
+		log.Printf("credited artists on %d songs and %d albums", len(songs), len(albums))
+	}
+	return nil
+}
+
+// artistResolver returns a lookup from artist name to ID, matched case
+// insensitively, that creates artists not in the catalog yet, and a check
+// for whether a name is already an artist.
+func artistResolver(db *gorm.DB) (func(name string) (int, error), func(name string) bool, error) {
+	var artists []models.Artist
+	if err := db.Find(&artists).Error; err != nil {
+		return nil, nil, err
+	}
+	byName := make(map[string]int, len(artists))
+	for _, a := range artists {
+		byName[strings.ToLower(a.Name)] = a.ArtistId
+	}
+	resolve := func(name string) (int, error) {
+		if id, ok := byName[strings.ToLower(name)]; ok {
+			return id, nil
+		}
+		a := models.Artist{Name: name}
+		if err := db.Create(&a).Error; err != nil {
+			return 0, err
+		}
+		byName[strings.ToLower(name)] = a.ArtistId
+		return a.ArtistId, nil
+	}
+	known := func(name string) bool {
+		_, ok := byName[strings.ToLower(name)]
+		return ok
+	}
+	return resolve, known, nil
+}
+
+// seedTrackCredits adds the writers, producers and label from the credits
+// section of defaults.json. Songs that already have writer or producer
+// credits are left alone, so edits made since seeding survive restarts.
+func seedTrackCredits(db *gorm.DB, entries []models.TrackCredits) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var credited []int
+	if err := db.
+		Model(&models.SongCreditName{}).
+		Distinct().
+		Pluck("song_id", &credited).
+		Error; err != nil {
+		return err
+	}
+	done := make(map[int]bool, len(credited))
+	for _, id := range credited {
+		done[id] = true
+	}
+
+	seeded := 0
+	for _, e := range entries {
+		if done[e.SongID] {
+			continue
+		}
+		var song models.Song
+		if err := db.First(&song, "id = ?", e.SongID).Error; err != nil {
+			log.Printf("skipping credits for unknown song %d", e.SongID)
+			continue
+		}
+		if e.Label != "" && song.Label == "" {
+			if err := db.Model(&song).Update("label", e.Label).Error; err != nil {
+				return err
+			}
+		}
+		for role, names := range map[string][]string{
+			models.RoleWriter:   e.Writers,
+			models.RoleProducer: e.Producers,
+		} {
+			for pos, name := range names {
+				if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+					Create(&models.SongCreditName{SongID: e.SongID, Role: role, Position: pos, Name: name}).
+					Error; err != nil {
+					return err
+				}
+			}
+		}
+		seeded++
+	}
+	if seeded > 0 {
+		log.Printf("seeded credits for %d songs", seeded)
+	}
+	return nil
+}
+
+// repairSystemUser fixes databases seeded while the system user was created
+// before the default users: it took ID 1 and the default users were never
+// seeded. The default users are restored, the system user gets a new ID,
+// and editorial and generated playlists move over to it.
+func repairSystemUser(db *gorm.DB, defs Defaults) error {
+	var first models.User
+	if err := db.First(&first, "id = ?", 1).Error; err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if first.Name != models.SystemUserName || len(defs.Users) == 0 {
+		return nil
+	}
+	editorial := []int{0}
+	for _, p := range defs.Playlists {
+		if p.UserID == 0 {
+			editorial = append(editorial, p.ID)
+		}
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&defs.Users).Error; err != nil {
+			return err
+		}
+		system := models.User{Name: models.SystemUserName, Image: first.Image}
+		if err := tx.Create(&system).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Model(&models.Playlist{}).
+			Where("user_id = ? AND (id IN ? OR generator IN ('daily_mix', 'discover_weekly'))", first.ID, editorial).
+			Update("user_id", system.ID).
+			Error; err != nil {
+			return err
+		}
+		log.Printf("restored default users; system user is now %d", system.ID)
+		return nil
+	})
+}
+
+// migrateCreditNames moves writer and producer credits that point at an
+// Artist into song_credit_names, then deletes the artists that were only
+// created for them: ones not in defaults.json that nothing else refers to.
+func migrateCreditNames(db *gorm.DB, seeded []models.Artist) error {
+	var credits []models.SongCredit
+	if err := db.
+		Preload("Artist").
+		Where("role IN ?", []string{models.RoleWriter, models.RoleProducer}).
+		Find(&credits).
+		Error; err != nil {
+		return err
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+	keep := []int{0}
+	for _, a := range seeded {
+		keep = append(keep, a.ArtistId)
+	}
+	var moved []int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, cr := range credits {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.SongCreditName{SongID: cr.SongID, Role: cr.Role, Position: cr.Position, Name: cr.Artist.Name}).
+				Error; err != nil {
+				return err
+			}
+			moved = append(moved, cr.ArtistID)
+		}
+		if err := tx.
+			Where("role IN ?", []string{models.RoleWriter, models.RoleProducer}).
+			Delete(&models.SongCredit{}).
+			Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			DELETE FROM artists
+			WHERE artist_id IN ? AND artist_id NOT IN ?
+			  AND artist_id NOT IN (SELECT artist_id FROM songs)
+			  AND artist_id NOT IN (SELECT artist_id FROM albums)
+			  AND artist_id NOT IN (SELECT artist_id FROM song_credits)
+			  AND artist_id NOT IN (SELECT artist_id FROM album_credits)
+			  AND artist_id NOT IN (SELECT reference_id FROM library_entries WHERE type = 'artist')
+			  AND artist_id NOT IN (SELECT origin_id FROM recent_plays WHERE type = 'artist')`,
+			moved, keep).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("moved %d writer and producer credits to credit names", len(credits))
+	return nil
+}
+
+// migratePodcastEpisodes creates a podcast_episodes row for every episode
+// still in a podcast's Episodes JSON, then points that show's episode
+// downloads and plays, keyed by the old per-show IDs, at the new global
+// IDs and clears the JSON. Each podcast moves in one transaction.
+func migratePodcastEpisodes(db *gorm.DB) error {
+	var podcasts []models.Podcast
+	if err := db.Where("episodes IS NOT NULL AND episodes NOT IN ('', 'null', '[]')").Find(&podcasts).Error; err != nil {
+		return err
+	}
+	for _, p := range podcasts {
+		var legacy []models.LegacyEpisode
+		if err := json.Unmarshal(p.Episodes, &legacy); err != nil {
+			return fmt.Errorf("podcast %d: %w", p.ID, err)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			ids := make(map[int]int, len(legacy)) // old per-show ID → global ID
+			for i, le := range legacy {
+				ep := models.PodcastEpisode{
+					PodcastID:   p.ID,
+					Number:      le.ID,
+					Title:       le.Title,
+					Description: le.Description,
+					Duration:    le.Duration,
+					AudioURL:    le.AudioURL,
+					Explicit:    le.Explicit,
+					ReleaseDate: time.Now().UTC(),
+				}
+				if ep.Number == 0 {
+					ep.Number = i + 1
+				}
+				if le.ReleaseDate != nil {
+					ep.ReleaseDate = le.ReleaseDate.UTC()
+				}
+				if err := tx.Create(&ep).Error; err != nil {
+					return err
+				}
+				ids[ep.Number] = ep.ID
+			}
+
+			// remap through negative IDs so an old ID that equals another
+			// episode's new ID is never overwritten twice
+			for old, id := range ids {
+				if err := tx.Model(&models.Download{}).
+					Where("type = ? AND parent_id = ? AND reference_id = ?", "episode", p.ID, old).
+					Update("reference_id", -id).Error; err != nil {
+					return err
+				}
+				if err := tx.Model(&models.RecentPlay{}).
+					Where("item_type = ? AND origin_id = ? AND reference_id = ?", "episode", p.ID, old).
+					Update("reference_id", -id).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&models.Download{}).
+				Where("type = ? AND parent_id = ? AND reference_id < 0", "episode", p.ID).
+				Update("reference_id", gorm.Expr("-reference_id")).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.RecentPlay{}).
+				Where("item_type = ? AND origin_id = ? AND reference_id < 0", "episode", p.ID).
+				Update("reference_id", gorm.Expr("-reference_id")).Error; err != nil {
+				return err
+			}
+			return tx.Model(&p).Update("episodes", nil).Error
+		})
+		if err != nil {
+			return fmt.Errorf("podcast %d: %w", p.ID, err)
+		}
+		log.Printf("moved %d episodes of podcast %d into their own table", len(legacy), p.ID)
+	}
+	return nil
+}